@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tags"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/upload"
+)
+
+var audioExtensions = map[string]bool{
+	".mp3":  true,
+	".flac": true,
+	".wav":  true,
+	".ogg":  true,
+	".opus": true,
+	".m4a":  true,
+	".aac":  true,
+}
+
+type importView struct {
+	File    string `json:"file"`
+	TrackID int64  `json:"track_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// importDir uploads the audio files under a directory the same way the upload endpoint does,
+// without quota checks. The file name without extension becomes the title.
+func (c *ctl) importDir(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	userID := flags.Int64("user", 0, "owner of the imported tracks")
+	visibility := flags.String("visibility", models.VisibilityPublic, "visibility of the imported tracks")
+	genre := flags.String("genre", "", "genre slug of the imported tracks")
+	tagList := flags.String("tags", "", "comma separated tags of the imported tracks")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *userID == 0 {
+		return errUsage
+	}
+
+	user, err := c.storage.UserByID(ctx, *userID)
+	if err != nil {
+		return fmt.Errorf("can't load user %d: %w", *userID, err)
+	}
+
+	var files []string
+	err = filepath.WalkDir(flags.Arg(0), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && audioExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	taxonomy := upload.Taxonomy{
+		Genre: *genre,
+		Tags:  tags.Split(*tagList),
+	}
+
+	uploader := upload.New(c.log, c.storage, c.media, c.broker, nil, c.cfg.MinioStorage.OriginalBucket)
+
+	var failed int
+	results := make([]importView, 0, len(files))
+	t := table{header: []string{"FILE", "TRACK", "ERROR"}}
+	for _, path := range files {
+		if ctx.Err() != nil {
+			break
+		}
+
+		res := importView{File: path}
+		id, err := c.importFile(ctx, uploader, user, *visibility, taxonomy, path)
+		if err != nil {
+			failed++
+			res.Error = err.Error()
+		}
+		res.TrackID = id
+
+		results = append(results, res)
+		t.rows = append(t.rows, []string{path, strconv.FormatInt(id, 10), res.Error})
+	}
+
+	if err := c.out.print(results, t); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	}
+	return nil
+}
+
+func (c *ctl) importFile(ctx context.Context, uploader *upload.UploadService, user models.User, visibility string, taxonomy upload.Taxonomy, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	name := filepath.Base(path)
+	title := strings.TrimSuffix(name, filepath.Ext(name))
+
+	return uploader.UploadTrack(ctx, user, title, visibility, name, file, st.Size(), nil, taxonomy)
+}
@@ -0,0 +1,144 @@
+// Command musicctl does operational work against the database, MinIO and the task queue
+// that the service and the worker share.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Sheridanlk/Music-Service/internal/broker"
+	"github.com/Sheridanlk/Music-Service/internal/config"
+	"github.com/Sheridanlk/Music-Service/internal/storage/media"
+	"github.com/Sheridanlk/Music-Service/internal/storage/postgresql"
+)
+
+const usage = `usage: musicctl [-o table|json] <command> [flags] [args]
+
+commands:
+  tracks [-status S] [-limit N] [-offset N]   list tracks, optionally by status,
+         [-after CURSOR]                      continuing from the cursor of the previous page
+  show ID                                     show a track and its MinIO objects
+  requeue [-task hls|waveform|preview] ID...  send tasks for tracks without changing their status
+  reprocess ID...                             reset ready or failed tracks to pending and process them again
+  import -user ID [-visibility V] DIR         upload every audio file in DIR,
+         [-genre SLUG] [-tags T1,T2]          optionally with a genre and tags
+  purge-orphans [-dry-run]                    delete objects of tracks that no longer exist
+  queue inspect [-queue Q] [-peek N]          show a task queue and the messages at its head
+  queue replay [-status S]                    send tasks for all tracks in status S (default pending)`
+
+var errUsage = errors.New("invalid usage")
+
+type ctl struct {
+	log *slog.Logger
+	cfg *config.Config
+	out printer
+
+	storage *postgresql.Storage
+	media   *media.MinioStorage
+	broker  *broker.RabbitMQ
+}
+
+func main() {
+	flags := flag.NewFlagSet("musicctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	format := flags.String("o", formatTable, "output format: table or json")
+	_ = flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 || (*format != formatTable && *format != formatJSON) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+
+	// Logs go to stderr so they don't mix with the command output.
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	c, err := connect(log, cfg, *format)
+	if err != nil {
+		log.Error("failed to connect", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer c.close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := c.run(ctx, flags.Arg(0), flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		c.close()
+		os.Exit(1)
+	}
+}
+
+func connect(log *slog.Logger, cfg *config.Config, format string) (*ctl, error) {
+	storage, err := postgresql.New(cfg.PostgreSQL.Host, cfg.PostgreSQL.UserName, cfg.PostgreSQL.Password, cfg.PostgreSQL.DBName, cfg.PostgreSQL.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	minioStorage, err := media.New(cfg.MinIOClient.Endpoint, cfg.MinIOClient.AccessKeyID, cfg.MinIOClient.SecretAccessKey, cfg.MinIOClient.UseSSL)
+	if err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	taskBroker, err := broker.New(cfg.RabbitMQ.UserName, cfg.RabbitMQ.Password, cfg.RabbitMQ.Host, cfg.RabbitMQ.Port)
+	if err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	return &ctl{
+		log:     log,
+		cfg:     cfg,
+		out:     printer{format: format, w: os.Stdout},
+		storage: storage,
+		media:   minioStorage,
+		broker:  taskBroker,
+	}, nil
+}
+
+func (c *ctl) close() {
+	c.broker.Close()
+	c.storage.Close()
+}
+
+func (c *ctl) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "tracks":
+		return c.listTracks(ctx, args)
+	case "show":
+		return c.showTrack(ctx, args)
+	case "requeue":
+		return c.requeue(ctx, args)
+	case "reprocess":
+		return c.reprocess(ctx, args)
+	case "import":
+		return c.importDir(ctx, args)
+	case "purge-orphans":
+		return c.purgeOrphans(ctx, args)
+	case "queue":
+		if len(args) == 0 {
+			return errUsage
+		}
+		switch args[0] {
+		case "inspect":
+			return c.inspectQueue(args[1:])
+		case "replay":
+			return c.replayQueue(ctx, args[1:])
+		}
+	}
+
+	return errUsage
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+// table describes how a value is printed in table mode. In JSON mode the value itself is encoded.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (p printer) print(v any, t table) error {
+	if p.format == formatJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	return p.table(t)
+}
+
+func (p printer) table(t table) error {
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	if len(t.header) > 0 {
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+)
+
+// purgeOrphans deletes objects under tracks/{id}/ whose track row no longer exists,
+// for example after a track was deleted while MinIO was unavailable.
+func (c *ctl) purgeOrphans(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("purge-orphans", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only list orphaned objects")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	buckets := []string{c.cfg.MinioStorage.OriginalBucket}
+	if c.cfg.MinioStorage.HLSBucket != c.cfg.MinioStorage.OriginalBucket {
+		buckets = append(buckets, c.cfg.MinioStorage.HLSBucket)
+	}
+
+	orphans := make([]objectView, 0)
+	t := table{header: []string{"BUCKET", "KEY", "SIZE"}}
+	for _, bucket := range buckets {
+		objects, err := c.media.ListObjects(ctx, bucket, "tracks/")
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(objects))
+		for _, obj := range objects {
+			if id, ok := media.ParseTrackKey(obj.Key); ok {
+				ids = append(ids, id)
+			}
+		}
+
+		existing, err := c.storage.ExistingTrackIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		for _, obj := range objects {
+			id, ok := media.ParseTrackKey(obj.Key)
+			if !ok || existing[id] {
+				continue
+			}
+			keys = append(keys, obj.Key)
+			orphans = append(orphans, objectView{
+				Bucket:       bucket,
+				Key:          obj.Key,
+				Size:         obj.Size,
+				ContentType:  obj.ContentType,
+				LastModified: obj.LastModified,
+			})
+			t.rows = append(t.rows, []string{bucket, obj.Key, strconv.FormatInt(obj.Size, 10)})
+		}
+
+		if !*dryRun && len(keys) > 0 {
+			if err := c.media.RemoveObjects(ctx, bucket, keys); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.out.print(orphans, t); err != nil {
+		return err
+	}
+	if c.out.format == formatTable {
+		verb := "removed"
+		if *dryRun {
+			verb = "would remove"
+		}
+		fmt.Fprintf(c.out.w, "\n%s %d objects\n", verb, len(orphans))
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/broker"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+const replayPageSize = 500
+
+type queueView struct {
+	Name      string        `json:"name"`
+	Messages  int           `json:"messages"`
+	Consumers int           `json:"consumers"`
+	Head      []messageView `json:"head"`
+}
+
+type messageView struct {
+	Body        string `json:"body"`
+	Redelivered bool   `json:"redelivered"`
+}
+
+func (c *ctl) inspectQueue(args []string) error {
+	flags := flag.NewFlagSet("queue inspect", flag.ContinueOnError)
+	queue := flags.String("queue", broker.AudioTasksQueue, "queue to inspect")
+	peek := flags.Int("peek", 0, "number of messages to show from the head of the queue")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	state, err := c.broker.InspectQueue(*queue, *peek)
+	if err != nil {
+		return err
+	}
+
+	view := queueView{
+		Name:      state.Name,
+		Messages:  state.Messages,
+		Consumers: state.Consumers,
+		Head:      make([]messageView, 0, len(state.Head)),
+	}
+	t := table{
+		header: []string{"QUEUE", "MESSAGES", "CONSUMERS"},
+		rows:   [][]string{{state.Name, strconv.Itoa(state.Messages), strconv.Itoa(state.Consumers)}},
+	}
+	for _, msg := range state.Head {
+		view.Head = append(view.Head, messageView{Body: msg.Body, Redelivered: msg.Redelivered})
+	}
+
+	if c.out.format == formatJSON || len(view.Head) == 0 {
+		return c.out.print(view, t)
+	}
+
+	if err := c.out.table(t); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out.w)
+
+	head := table{header: []string{"#", "TRACK", "REDELIVERED"}}
+	for i, msg := range view.Head {
+		head.rows = append(head.rows, []string{strconv.Itoa(i + 1), msg.Body, strconv.FormatBool(msg.Redelivered)})
+	}
+	return c.out.table(head)
+}
+
+// replayQueue publishes a task for every track in the status, for example after the queue was purged.
+func (c *ctl) replayQueue(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("queue replay", flag.ContinueOnError)
+	status := flags.String("status", storage.StatusPending, "status of the tracks to replay")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || *status == "" {
+		return errUsage
+	}
+
+	var ids []string
+	var after *models.TrackCursor
+	for {
+		tracks, err := c.storage.ListTracksByStatus(ctx, *status, replayPageSize, 0, after)
+		if err != nil {
+			return err
+		}
+		for _, track := range tracks {
+			ids = append(ids, strconv.FormatInt(track.ID, 10))
+		}
+		if len(tracks) < replayPageSize {
+			break
+		}
+		last := tracks[len(tracks)-1]
+		after = &models.TrackCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	if len(ids) == 0 {
+		return c.out.print([]resultView{}, table{header: []string{"ID", "RESULT"}})
+	}
+
+	return c.requeue(ctx, ids)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/cursor"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+const (
+	taskHLS      = "hls"
+	taskWaveform = "waveform"
+	taskPreview  = "preview"
+)
+
+type trackView struct {
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	Status     string    `json:"status"`
+	Visibility string    `json:"visibility"`
+	Hidden     bool      `json:"hidden"`
+	UploadedBy *int64    `json:"uploaded_by,omitempty"`
+	SizeBytes  int64     `json:"size_bytes"`
+	OriginKey  string    `json:"origin_key"`
+	HLSPrefix  string    `json:"hls_prefix,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type objectView struct {
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type resultView struct {
+	TrackID int64  `json:"track_id"`
+	Result  string `json:"result"`
+}
+
+func newTrackView(t models.Track) trackView {
+	v := trackView{
+		ID:         t.ID,
+		Title:      t.Title,
+		Status:     t.Status,
+		Visibility: t.Visibility,
+		Hidden:     t.Hidden,
+		UploadedBy: t.UploadedBy,
+		SizeBytes:  t.SizeBytes,
+		OriginKey:  t.OriginKey,
+		CreatedAt:  t.CreatedAt,
+	}
+	if t.HLSPrefix != nil {
+		v.HLSPrefix = *t.HLSPrefix
+	}
+	return v
+}
+
+func (c *ctl) listTracks(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("tracks", flag.ContinueOnError)
+	status := flags.String("status", "", "only tracks in this status")
+	limit := flags.Int("limit", 50, "maximum number of tracks")
+	offset := flags.Int("offset", 0, "number of tracks to skip")
+	afterToken := flags.String("after", "", "cursor printed by the previous page, replaces -offset")
+	if err := flags.Parse(args); err != nil || *limit <= 0 {
+		return errUsage
+	}
+
+	var after *models.TrackCursor
+	if *afterToken != "" {
+		position, err := cursor.DecodeTrack(*afterToken, models.SortNewest)
+		if err != nil {
+			return err
+		}
+		after = &position
+	}
+
+	tracks, err := c.storage.ListTracksByStatus(ctx, *status, *limit, *offset, after)
+	if err != nil {
+		return err
+	}
+
+	// The cursor goes to stderr so the listing itself stays parseable.
+	if len(tracks) == *limit {
+		last := tracks[len(tracks)-1]
+		fmt.Fprintln(os.Stderr, "next page: -after", cursor.EncodeTrack(models.TrackCursor{Sort: models.SortNewest, CreatedAt: last.CreatedAt, ID: last.ID}))
+	}
+
+	views := make([]trackView, 0, len(tracks))
+	t := table{header: []string{"ID", "STATUS", "VISIBILITY", "SIZE", "CREATED", "TITLE"}}
+	for _, track := range tracks {
+		views = append(views, newTrackView(track))
+		t.rows = append(t.rows, []string{
+			strconv.FormatInt(track.ID, 10),
+			track.Status,
+			track.Visibility,
+			strconv.FormatInt(track.SizeBytes, 10),
+			track.CreatedAt.Format(time.RFC3339),
+			track.Title,
+		})
+	}
+
+	return c.out.print(views, t)
+}
+
+func (c *ctl) showTrack(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid track id: %s", args[0])
+	}
+
+	track, err := c.storage.GetTrack(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Every object of a track lives under tracks/{id}/ in the origin and HLS buckets.
+	prefix := fmt.Sprintf("tracks/%d/", id)
+	buckets := []string{track.OriginBucket}
+	if track.HLSBucket != nil && *track.HLSBucket != track.OriginBucket {
+		buckets = append(buckets, *track.HLSBucket)
+	}
+
+	objects := make([]objectView, 0)
+	for _, bucket := range buckets {
+		infos, err := c.media.ListObjects(ctx, bucket, prefix)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			objects = append(objects, objectView{
+				Bucket:       bucket,
+				Key:          info.Key,
+				Size:         info.Size,
+				ContentType:  info.ContentType,
+				LastModified: info.LastModified,
+			})
+		}
+	}
+
+	view := newTrackView(track)
+	if c.out.format == formatJSON {
+		return c.out.print(struct {
+			Track   trackView    `json:"track"`
+			Objects []objectView `json:"objects"`
+		}{view, objects}, table{})
+	}
+
+	if err := c.out.table(table{rows: [][]string{
+		{"id:", strconv.FormatInt(view.ID, 10)},
+		{"title:", view.Title},
+		{"status:", view.Status},
+		{"visibility:", view.Visibility},
+		{"hidden:", strconv.FormatBool(view.Hidden)},
+		{"size:", strconv.FormatInt(view.SizeBytes, 10)},
+		{"origin:", track.OriginBucket + "/" + view.OriginKey},
+		{"hls:", view.HLSPrefix},
+		{"created:", view.CreatedAt.Format(time.RFC3339)},
+	}}); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out.w)
+
+	t := table{header: []string{"BUCKET", "KEY", "SIZE", "TYPE", "MODIFIED"}}
+	for _, obj := range objects {
+		t.rows = append(t.rows, []string{obj.Bucket, obj.Key, strconv.FormatInt(obj.Size, 10), obj.ContentType, obj.LastModified.Format(time.RFC3339)})
+	}
+	return c.out.table(t)
+}
+
+// requeue publishes tasks without touching the status, for tracks whose task was lost.
+func (c *ctl) requeue(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("requeue", flag.ContinueOnError)
+	task := flags.String("task", taskHLS, "task to send: hls, waveform or preview")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	send := c.broker.SendTrackTask
+	switch *task {
+	case taskHLS:
+	case taskWaveform:
+		send = c.broker.SendWaveformTask
+	case taskPreview:
+		send = c.broker.SendPreviewTask
+	default:
+		return errUsage
+	}
+
+	return c.forEachTrack(ctx, flags.Args(), func(id int64) error {
+		if _, err := c.storage.GetTrack(ctx, id); err != nil {
+			return err
+		}
+		return send(ctx, strconv.FormatInt(id, 10))
+	})
+}
+
+// reprocess moves ready or failed tracks back to pending and publishes their tasks.
+func (c *ctl) reprocess(ctx context.Context, args []string) error {
+	return c.forEachTrack(ctx, args, func(id int64) error {
+		if err := c.storage.ResetStatusPending(ctx, id); err != nil {
+			return err
+		}
+		return c.broker.SendTrackTask(ctx, strconv.FormatInt(id, 10))
+	})
+}
+
+// forEachTrack runs fn for every track ID in args and prints a result per track.
+func (c *ctl) forEachTrack(ctx context.Context, args []string, fn func(id int64) error) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid track id: %s", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	var failed int
+	results := make([]resultView, 0, len(ids))
+	t := table{header: []string{"ID", "RESULT"}}
+	for _, id := range ids {
+		res := "queued"
+		if err := fn(id); err != nil {
+			failed++
+			res = err.Error()
+			if errors.Is(err, storage.ErrNotFound) {
+				res = "not found"
+			}
+		}
+		results = append(results, resultView{TrackID: id, Result: res})
+		t.rows = append(t.rows, []string{strconv.FormatInt(id, 10), res})
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err := c.out.print(results, t); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tracks failed", failed, len(ids))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/Sheridanlk/Music-Service/internal/app"
 	"github.com/Sheridanlk/Music-Service/internal/config"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
 	"github.com/Sheridanlk/Music-Service/internal/logger"
 )
 
@@ -15,9 +17,28 @@ func main() {
 	cfg := config.Load()
 	log := logger.SetupLogger(cfg.Env)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.PostgreSQL, os.Args[2:]); err != nil {
+			log.Error("migrate failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Info("starting music service", slog.String("env", cfg.Env))
 
-	application := app.New(log, cfg.PostgreSQL, cfg.HTTPServer, cfg.MinIOClient, cfg.MinioStorage, cfg.RabbitMQ)
+	shutdownTracing, err := tracing.Setup(context.Background(), "music-service", tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to setup tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	application := app.New(log, cfg.PostgreSQL, cfg.HTTPServer, cfg.MinIOClient, cfg.MinioStorage, cfg.RabbitMQ, cfg.StreamCache, cfg.StreamSigning, cfg.HLS, cfg.Waveform, cfg.Cover, cfg.Preview, cfg.Download, cfg.Auth, cfg.RateLimit, cfg.Quotas, cfg.Metrics, cfg.Health)
 
 	go application.Server.Start()
 
@@ -29,6 +50,10 @@ func main() {
 	application.Server.Stop()
 	application.Storage.Close()
 
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Error("failed to flush traces", slog.String("error", err.Error()))
+	}
+
 	log.Info("application stopped")
 
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Sheridanlk/Music-Service/internal/config"
+	"github.com/Sheridanlk/Music-Service/internal/storage/postgresql"
+	"github.com/Sheridanlk/Music-Service/migrations"
+)
+
+const migrateUsage = `usage: service migrate <command>
+
+commands:
+  up           apply all pending migrations
+  down N       roll back the last N migrations
+  status       show the current version and pending migrations
+  force V      set the version to V and clear the dirty flag, -1 for an empty database`
+
+var errUsage = errors.New(migrateUsage)
+
+// runMigrate executes the migrate subcommand against the configured database.
+func runMigrate(cfg config.PostgreSQL, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	storage, err := postgresql.New(cfg.Host, cfg.UserName, cfg.Password, cfg.DBName, cfg.Port)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := storage.MigrateUp(ctx, migrations.FS)
+		for _, v := range applied {
+			fmt.Printf("applied %d\n", v)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no change")
+		}
+
+	case "down":
+		if len(args) != 2 {
+			return errUsage
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations: %s", args[1])
+		}
+
+		reverted, err := storage.MigrateDown(ctx, migrations.FS, n)
+		for _, v := range reverted {
+			fmt.Printf("reverted %d\n", v)
+		}
+		if err != nil {
+			return err
+		}
+
+	case "status":
+		state, err := storage.MigrationStatus(ctx, migrations.FS)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("version: %d, dirty: %t\n\n", state.Version, state.Dirty)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+		for _, m := range state.Migrations {
+			status := "pending"
+			if m.Version <= state.Version {
+				status = "applied"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, status)
+		}
+		return w.Flush()
+
+	case "force":
+		if len(args) != 2 {
+			return errUsage
+		}
+		v, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+
+		if err := storage.ForceVersion(ctx, migrations.FS, v); err != nil {
+			return err
+		}
+		fmt.Printf("forced version %d\n", v)
+
+	default:
+		return errUsage
+	}
+
+	return nil
+}
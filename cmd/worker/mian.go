@@ -8,6 +8,7 @@ import (
 
 	"github.com/Sheridanlk/Music-Service/internal/app/worker"
 	"github.com/Sheridanlk/Music-Service/internal/config"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
 	"github.com/Sheridanlk/Music-Service/internal/logger"
 )
 
@@ -17,7 +18,18 @@ func main() {
 
 	log.Info("starting worker", "env", cfg.Env)
 
-	app := worker.New(log, cfg.PostgreSQL, cfg.MinIOClient, cfg.MinioStorage, cfg.RabbitMQ)
+	shutdownTracing, err := tracing.Setup(context.Background(), "music-worker", tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to setup tracing", "error", err)
+		os.Exit(1)
+	}
+
+	app := worker.New(log, cfg.PostgreSQL, cfg.MinIOClient, cfg.MinioStorage, cfg.RabbitMQ, cfg.HLS, cfg.Waveform, cfg.Cover, cfg.Preview, cfg.Download, cfg.Quotas, cfg.Metrics, cfg.Health)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -28,5 +40,9 @@ func main() {
 
 	app.Stop()
 
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Error("failed to flush traces", "error", err)
+	}
+
 	log.Info("worker stopped")
 }
@@ -1,20 +1,51 @@
 package app
 
 import (
+	"context"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/Sheridanlk/Music-Service/internal/app/server"
+	"github.com/Sheridanlk/Music-Service/internal/authz"
 	"github.com/Sheridanlk/Music-Service/internal/broker"
 	"github.com/Sheridanlk/Music-Service/internal/config"
+	downloadhandler "github.com/Sheridanlk/Music-Service/internal/http/handlers/track/download"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/key"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/list"
+	ratelimitmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/ratelimit"
 	"github.com/Sheridanlk/Music-Service/internal/http/router/chi"
-	"github.com/Sheridanlk/Music-Service/internal/services/tracks/list"
+	"github.com/Sheridanlk/Music-Service/internal/lib/health"
+	"github.com/Sheridanlk/Music-Service/internal/lib/keywrap"
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/ratelimit"
+	"github.com/Sheridanlk/Music-Service/internal/lib/streamtoken"
+	"github.com/Sheridanlk/Music-Service/internal/services/apikeys"
+	"github.com/Sheridanlk/Music-Service/internal/services/auth"
+	"github.com/Sheridanlk/Music-Service/internal/services/genres"
+	"github.com/Sheridanlk/Music-Service/internal/services/quota"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/cover"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/download"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/keys"
+	tracklist "github.com/Sheridanlk/Music-Service/internal/services/tracks/list"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/lyrics"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/manage"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/shares"
 	"github.com/Sheridanlk/Music-Service/internal/services/tracks/stream"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/stream/cache"
 	"github.com/Sheridanlk/Music-Service/internal/services/tracks/upload"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/waveform"
 	"github.com/Sheridanlk/Music-Service/internal/storage/media"
 	"github.com/Sheridanlk/Music-Service/internal/storage/postgresql"
+	"github.com/Sheridanlk/Music-Service/migrations"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// migrateTimeout bounds auto-migration, including waiting for another replica holding the lock.
+const migrateTimeout = 5 * time.Minute
+
 type App struct {
 	Storage *postgresql.Storage
 	Server  *server.App
@@ -27,6 +58,18 @@ func New(
 	minioClientCfg config.MinIOClient,
 	minioStorageCfg config.MinioStorage,
 	rabbitCfg config.RabbitMQ,
+	streamCacheCfg config.StreamCache,
+	streamSigningCfg config.StreamSigning,
+	hlsCfg config.HLS,
+	waveformCfg config.Waveform,
+	coverCfg config.Cover,
+	previewCfg config.Preview,
+	downloadCfg config.Download,
+	authCfg config.Auth,
+	rateLimitCfg config.RateLimit,
+	quotaCfg config.Quotas,
+	metricsCfg config.Metrics,
+	healthCfg config.Health,
 ) *App {
 	storage, err := postgresql.New(storageCfg.Host, storageCfg.UserName, storageCfg.Password, storageCfg.DBName, storageCfg.Port)
 	if err != nil {
@@ -34,6 +77,17 @@ func New(
 		os.Exit(1)
 	}
 
+	if storageCfg.AutoMigrate {
+		ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+		applied, err := storage.MigrateUp(ctx, migrations.FS)
+		cancel()
+		if err != nil {
+			log.Error("failed to apply migrations", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		log.Info("migrations applied", slog.Int("count", len(applied)))
+	}
+
 	minioStorage, err := media.New(minioClientCfg.Endpoint, minioClientCfg.AccessKeyID, minioClientCfg.SecretAccessKey, minioClientCfg.UseSSL)
 	if err != nil {
 		log.Error("failed to init minio storage", slog.String("error", err.Error()))
@@ -47,11 +101,165 @@ func New(
 		// TODO: retries
 	}
 
-	trackUploaderService := upload.New(log, storage, minioStorage, taskBroker, minioStorageCfg.OriginalBucket)
-	trackStreamerService := stream.New(log, storage, minioStorage)
-	trackListerService := list.New(log, storage)
+	var streamMedia stream.MediaProvider = minioStorage
+	var segmentCache *cache.Cache
+	if streamCacheCfg.Enabled {
+		segmentCache, err = cache.New(log, minioStorage, cache.Config{
+			MemoryLimit:   streamCacheCfg.MemoryLimitMB << 20,
+			MaxObjectSize: streamCacheCfg.MaxObjectMB << 20,
+			TTL:           streamCacheCfg.TTL,
+			PlaylistTTL:   streamCacheCfg.PlaylistTTL,
+			DiskDir:       streamCacheCfg.DiskDir,
+			DiskLimit:     streamCacheCfg.DiskLimitMB << 20,
+		})
+		if err != nil {
+			log.Error("failed to init stream cache", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		streamMedia = segmentCache
+	}
+
+	var streamSigner list.StreamSigner
+	var tokenVerifier stream.TokenVerifier
+	if streamSigningCfg.Enabled {
+		keys, err := streamtoken.ParseKeys(streamSigningCfg.Keys)
+		if err != nil {
+			log.Error("failed to parse stream signing keys", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		signer, err := streamtoken.New(keys, streamSigningCfg.ActiveKey, streamSigningCfg.TTL)
+		if err != nil {
+			log.Error("failed to init stream signer", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		streamSigner = signer
+		tokenVerifier = signer
+	}
+
+	shareService := shares.New(log, storage, storage)
+	var quotaService chi.QuotaService
+	var uploadQuota upload.QuotaChecker
+	var entitlements stream.EntitlementChecker
+	var downloadEntitlements download.EntitlementChecker
+	if quotaCfg.Enabled {
+		if _, ok := quotaCfg.Plans[quotaCfg.DefaultPlan]; !ok {
+			log.Error("default plan is not configured", slog.String("plan", quotaCfg.DefaultPlan))
+			os.Exit(1)
+		}
+
+		service := quota.New(log, storage, storage, quotaCfg.PlanLimits(), quotaCfg.DefaultPlan)
+		quotaService = service
+		uploadQuota = service
+		entitlements = service
+		downloadEntitlements = service
+	}
+
+	trackUploaderService := upload.New(log, storage, minioStorage, taskBroker, uploadQuota, minioStorageCfg.OriginalBucket)
+	lyricsService := lyrics.New(log, storage, storage, shareService)
+	genreService := genres.New(log, storage)
+	trackStreamerService := stream.New(log, storage, streamMedia, tokenVerifier, shareService, entitlements, storage, previewCfg.Enabled)
+	trackListerService := tracklist.New(log, storage)
+	waveformService := waveform.New(log, storage, minioStorage, shareService, minioStorageCfg.HLSBucket, waveform.Profile{
+		SampleRate:  waveformCfg.SampleRate,
+		Resolutions: waveformCfg.Resolutions,
+	})
+	coverService := cover.New(log, storage, minioStorage, shareService, minioStorageCfg.HLSBucket, cover.Profile{
+		Sizes:   coverCfg.Sizes,
+		Quality: coverCfg.Quality,
+	})
+
+	var downloader downloadhandler.Downloader
+	if downloadCfg.Enabled {
+		var profile download.Profile
+		if coverCfg.Enabled && len(coverCfg.Sizes) > 0 {
+			profile.CoverSize = slices.Max(coverCfg.Sizes)
+		}
+		downloader = download.New(log, storage, minioStorage, taskBroker, downloadEntitlements, minioStorageCfg.HLSBucket, profile)
+	}
+
+	if authCfg.JWTSecret == "" {
+		log.Error("jwt secret is not set")
+		os.Exit(1)
+	}
+
+	if !authz.ValidRole(authCfg.DefaultRole) {
+		log.Error("invalid default role", slog.String("role", authCfg.DefaultRole))
+		os.Exit(1)
+	}
+
+	authService := auth.New(log, storage, storage, storage, authCfg.JWTSecret, authCfg.AccessTokenTTL, authCfg.RefreshTokenTTL, authCfg.DefaultRole)
+	apiKeyService := apikeys.New(log, storage, storage)
+	var previewProducer manage.PreviewProducer
+	if previewCfg.Enabled {
+		previewProducer = taskBroker
+	}
+	trackManagerService := manage.New(log, storage, taskBroker, previewProducer)
+
+	// keywrap.New rejects a missing key or one that is not 16, 24 or 32 bytes long.
+	var keyProvider key.KeyProvider
+	if hlsCfg.Encryption.Enabled {
+		wrapper, err := keywrap.New(hlsCfg.Encryption.KeyEncryptionKey)
+		if err != nil {
+			log.Error("failed to init key wrapper", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		keyProvider = keys.New(log, storage, storage, wrapper, tokenVerifier, shareService)
+	}
+
+	var limiter *ratelimitmw.Limiter
+	if rateLimitCfg.Enabled {
+		resolver, err := ratelimitmw.NewIPResolver(rateLimitCfg.TrustedProxies)
+		if err != nil {
+			log.Error("failed to parse trusted proxies", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		var store ratelimitmw.Store
+		switch rateLimitCfg.Store {
+		case "memory":
+			store = ratelimit.NewMemoryStore()
+		case "postgres":
+			store = storage
+			go purgeRateLimits(log, storage)
+		default:
+			log.Error("invalid rate limit store", slog.String("store", rateLimitCfg.Store))
+			os.Exit(1)
+		}
+
+		limiter = ratelimitmw.New(log, store, resolver, map[string]ratelimit.Limit{
+			ratelimitmw.GroupUpload: {Burst: rateLimitCfg.Upload.Burst, Period: rateLimitCfg.Upload.Period},
+			ratelimitmw.GroupList:   {Burst: rateLimitCfg.List.Burst, Period: rateLimitCfg.List.Period},
+			ratelimitmw.GroupStream: {Burst: rateLimitCfg.Stream.Burst, Period: rateLimitCfg.Stream.Period},
+		})
+	}
+
+	var metricsHandler http.Handler
+	if metricsCfg.Enabled {
+		collectors := []prometheus.Collector{metrics.NewTrackCollector(log, storage)}
+		if segmentCache != nil {
+			collectors = append(collectors, segmentCache)
+		}
+
+		if err := metrics.Register(collectors...); err != nil {
+			log.Error("failed to register metrics", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		metricsHandler = metrics.Handler()
+	}
+
+	checks := health.New(healthCfg.Timeout)
+	checks.Register("postgres", storage.Ping)
+	checks.Register("minio_original", func(ctx context.Context) error {
+		return minioStorage.CheckBucket(ctx, minioStorageCfg.OriginalBucket)
+	})
+	checks.Register("minio_hls", func(ctx context.Context) error {
+		return minioStorage.CheckBucket(ctx, minioStorageCfg.HLSBucket)
+	})
+	checks.Register("rabbitmq", taskBroker.Check)
 
-	router := chi.Setup(log, trackUploaderService, trackStreamerService, trackListerService)
+	router := chi.Setup(log, authService, authCfg.SecureCookies, apiKeyService, trackUploaderService, trackStreamerService, trackListerService, trackManagerService, shareService, quotaService, waveformService, coverService, downloader, lyricsService, genreService, keyProvider, streamSigner, limiter, metricsHandler, checks)
 
 	server := server.New(log, router, serverCfg.Host, serverCfg.Port, serverCfg.Timeout, serverCfg.Timeout, serverCfg.IdleTimeout)
 	return &App{
@@ -59,3 +267,15 @@ func New(
 		Server:  server,
 	}
 }
+
+// purgeRateLimits removes idle buckets from the shared rate limit store.
+func purgeRateLimits(log *slog.Logger, storage *postgresql.Storage) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := storage.PurgeRateLimits(context.Background()); err != nil {
+			log.Warn("failed to purge rate limits", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -2,23 +2,44 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
+	"time"
 
+	"github.com/Sheridanlk/Music-Service/internal/app/server"
 	"github.com/Sheridanlk/Music-Service/internal/app/worker/consumer"
 	"github.com/Sheridanlk/Music-Service/internal/broker"
 	"github.com/Sheridanlk/Music-Service/internal/config"
+	healthhandler "github.com/Sheridanlk/Music-Service/internal/http/handlers/health"
+	"github.com/Sheridanlk/Music-Service/internal/lib/health"
+	"github.com/Sheridanlk/Music-Service/internal/lib/keywrap"
+	medialib "github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/services/quota"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/cover"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/download"
 	"github.com/Sheridanlk/Music-Service/internal/services/tracks/hls"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/preview"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/waveform"
 	"github.com/Sheridanlk/Music-Service/internal/storage/media"
 	"github.com/Sheridanlk/Music-Service/internal/storage/postgresql"
 )
 
+const probeTimeout = 10 * time.Second
+
 type App struct {
-	storage  *postgresql.Storage
-	broker   *broker.RabbitMQ
-	consumer *consumer.HlsConsumer
+	log *slog.Logger
+
+	storage     *postgresql.Storage
+	broker      *broker.RabbitMQ
+	consumers   []*consumer.Consumer
+	probeServer *server.App
 
-	hlsConsumerDone <-chan struct{}
+	consumersDone []<-chan struct{}
 }
 
 func New(log *slog.Logger,
@@ -26,6 +47,14 @@ func New(log *slog.Logger,
 	minioClientCfg config.MinIOClient,
 	minioStorageCfg config.MinioStorage,
 	rabbitCfg config.RabbitMQ,
+	hlsCfg config.HLS,
+	waveformCfg config.Waveform,
+	coverCfg config.Cover,
+	previewCfg config.Preview,
+	downloadCfg config.Download,
+	quotaCfg config.Quotas,
+	metricsCfg config.Metrics,
+	healthCfg config.Health,
 ) *App {
 	storage, err := postgresql.New(storageCfg.Host, storageCfg.UserName, storageCfg.Password, storageCfg.DBName, storageCfg.Port)
 	if err != nil {
@@ -46,27 +75,202 @@ func New(log *slog.Logger,
 		// TODO: retries
 	}
 
-	hlsService := hls.New(log, storage, minioStorage, minioStorageCfg.HLSBucket)
+	var keyWrapper hls.KeyWrapper
+	if hlsCfg.Encryption.Enabled {
+		wrapper, err := keywrap.New(hlsCfg.Encryption.KeyEncryptionKey)
+		if err != nil {
+			log.Error("failed to init key wrapper", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		keyWrapper = wrapper
+	}
+
+	fmp4, err := segmentType(hlsCfg)
+	if err != nil {
+		log.Error("invalid hls config", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	profile := hls.Profile{
+		SegmentSeconds: hlsCfg.SegmentSeconds,
+		FMP4:           fmp4,
+		DASH:           hlsCfg.DASH,
+		Encrypt:        hlsCfg.Encryption.Enabled,
+		KeyRotation:    hlsCfg.Encryption.KeyRotation,
+		Waveform:       waveformCfg.Enabled,
+		Preview:        previewCfg.Enabled,
+		Loudness: hls.LoudnessProfile{
+			Measure:   hlsCfg.Loudness.Enabled,
+			Normalize: hlsCfg.Loudness.Normalize,
+			Target: medialib.LoudnessTarget{
+				Integrated: hlsCfg.Loudness.TargetLUFS,
+				TruePeak:   hlsCfg.Loudness.TruePeak,
+				Range:      hlsCfg.Loudness.Range,
+			},
+		},
+		Lossless: hls.LosslessProfile{
+			Enabled: hlsCfg.Lossless.Enabled,
+			Codec:   hlsCfg.Lossless.Codec,
+		},
+	}
+
+	var quotaChecker hls.QuotaChecker
+	if quotaCfg.Enabled {
+		quotaChecker = quota.New(log, storage, storage, quotaCfg.PlanLimits(), quotaCfg.DefaultPlan)
+	}
+
+	var covers hls.CoverGenerator
+	if coverCfg.Enabled {
+		covers = cover.New(log, storage, minioStorage, nil, minioStorageCfg.HLSBucket, cover.Profile{
+			Sizes:   coverCfg.Sizes,
+			Quality: coverCfg.Quality,
+		})
+	}
+
+	hlsService := hls.New(log, storage, minioStorage, storage, keyWrapper, quotaChecker, taskBroker, covers, minioStorageCfg.HLSBucket, profile)
 
 	msgs, _ := taskBroker.GetTrackTaskStream()
 
-	hlsConsumer := consumer.New(log, hlsService, msgs)
+	// The segmenter marks a track failed itself, an over-long track stays too long however often it is retried.
+	consumers := []*consumer.Consumer{consumer.New(log, "hls", hlsService.Hls, msgs, quota.ErrTooLong)}
+
+	if waveformCfg.Enabled {
+		waveformService := waveform.New(log, storage, minioStorage, nil, minioStorageCfg.HLSBucket, waveform.Profile{
+			SampleRate:  waveformCfg.SampleRate,
+			Resolutions: waveformCfg.Resolutions,
+		})
+
+		waveformMsgs, err := taskBroker.GetTaskStream(broker.WaveformTasksQueue)
+		if err != nil {
+			log.Error("failed to consume waveform tasks", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		consumers = append(consumers, consumer.New(log, "waveform", waveformService.Generate, waveformMsgs))
+	}
+
+	if previewCfg.Enabled {
+		previewService := preview.New(log, storage, minioStorage, minioStorageCfg.HLSBucket, preview.Profile{
+			Length:         previewCfg.Length,
+			SegmentSeconds: hlsCfg.SegmentSeconds,
+			FMP4:           fmp4,
+			DASH:           hlsCfg.DASH,
+		})
+
+		previewMsgs, err := taskBroker.GetTaskStream(broker.PreviewTasksQueue)
+		if err != nil {
+			log.Error("failed to consume preview tasks", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		consumers = append(consumers, consumer.New(log, "preview", previewService.Generate, previewMsgs))
+	}
+
+	if downloadCfg.Enabled {
+		var profile download.Profile
+		if coverCfg.Enabled && len(coverCfg.Sizes) > 0 {
+			profile.CoverSize = slices.Max(coverCfg.Sizes)
+		}
+		downloadService := download.New(log, storage, minioStorage, nil, nil, minioStorageCfg.HLSBucket, profile)
+
+		for _, format := range medialib.TranscodeFormats {
+			downloadMsgs, err := taskBroker.GetTaskStream(broker.DownloadTasksQueue(format))
+			if err != nil {
+				log.Error("failed to consume download tasks", slog.String("format", format), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+
+			transcode := func(ctx context.Context, id int64) error {
+				return downloadService.Transcode(ctx, id, format)
+			}
+			consumers = append(consumers, consumer.New(log, "download_"+format, transcode, downloadMsgs))
+		}
+	}
+
+	checks := health.New(healthCfg.Timeout)
+	checks.Register("postgres", storage.Ping)
+	checks.Register("minio_original", func(ctx context.Context) error {
+		return minioStorage.CheckBucket(ctx, minioStorageCfg.OriginalBucket)
+	})
+	checks.Register("minio_hls", func(ctx context.Context) error {
+		return minioStorage.CheckBucket(ctx, minioStorageCfg.HLSBucket)
+	})
+	checks.Register("rabbitmq", taskBroker.Check)
+	checks.Register("ffmpeg", medialib.CheckFFmpeg)
+	for _, c := range consumers {
+		checks.Register(c.Name()+"_consumer", c.Check)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /healthz", healthhandler.Live())
+	mux.Handle("GET /readyz", healthhandler.Ready(log, checks))
+
+	if metricsCfg.Enabled {
+		if err := metrics.Register(metrics.NewQueueCollector(log, taskBroker, broker.TaskQueues()...)); err != nil {
+			log.Error("failed to register metrics", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		mux.Handle("/metrics", metrics.Handler())
+	}
+
+	probeServer := server.New(log, mux, healthCfg.Host, healthCfg.Port, probeTimeout, probeTimeout, probeTimeout)
 
 	return &App{
-		storage:  storage,
-		broker:   taskBroker,
-		consumer: hlsConsumer,
+		log:         log,
+		storage:     storage,
+		broker:      taskBroker,
+		consumers:   consumers,
+		probeServer: probeServer,
 	}
 }
 
 func (a *App) Start(ctx context.Context) {
-	a.hlsConsumerDone = a.consumer.Consume(ctx)
+	for _, c := range a.consumers {
+		a.consumersDone = append(a.consumersDone, c.Consume(ctx))
+	}
+
+	go func() {
+		if err := a.probeServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.log.Error("probe server failed", slog.String("error", err.Error()))
+		}
+	}()
 }
 
 func (a *App) Stop() {
 	// TODO: add logs
-	<-a.hlsConsumerDone
+	for _, done := range a.consumersDone {
+		<-done
+	}
+	_ = a.probeServer.Stop()
 	a.broker.Close()
 	a.storage.Close()
 
 }
+
+// segmentType reports whether the renditions use fMP4 segments. Segment encryption only covers
+// packed audio, an fMP4 init segment would have to be encrypted as well. DASH manifests only list fMP4 segments.
+func segmentType(cfg config.HLS) (bool, error) {
+	var fmp4 bool
+	switch cfg.SegmentType {
+	case "", "aac":
+	case "fmp4":
+		fmp4 = true
+	default:
+		return false, fmt.Errorf("unknown segment type %q", cfg.SegmentType)
+	}
+
+	if cfg.Lossless.Enabled && cfg.Lossless.Codec != medialib.CodecFLAC && cfg.Lossless.Codec != medialib.CodecALAC {
+		return false, fmt.Errorf("unknown lossless codec %q", cfg.Lossless.Codec)
+	}
+
+	if cfg.DASH && !fmp4 {
+		return false, errors.New("dash needs fmp4 segments")
+	}
+
+	if cfg.Encryption.Enabled && (fmp4 || cfg.Lossless.Enabled) {
+		return false, errors.New("segment encryption only supports aac segments without a lossless rendition")
+	}
+
+	return fmp4, nil
+}
@@ -0,0 +1,153 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler processes the track a task refers to. A failed task is requeued unless the error is permanent.
+type Handler func(ctx context.Context, trackID int64) error
+
+// Consumer runs a Handler for every task delivered from one queue.
+type Consumer struct {
+	log      *slog.Logger
+	task     string
+	handle   Handler
+	messages <-chan amqp091.Delivery
+
+	permanent []error
+
+	running atomic.Bool
+}
+
+var ErrNotRunning = errors.New("consumer is not running")
+
+// New creates a consumer. task names the work in logs, traces and metrics. permanent lists the errors
+// a retry can't fix, tasks failing with one of them are dropped instead of requeued.
+func New(log *slog.Logger, task string, handle Handler, messages <-chan amqp091.Delivery, permanent ...error) *Consumer {
+	return &Consumer{
+		log:       log,
+		task:      task,
+		handle:    handle,
+		messages:  messages,
+		permanent: permanent,
+	}
+}
+
+// Name returns the task the consumer runs.
+func (h *Consumer) Name() string {
+	return h.task
+}
+
+func (h *Consumer) Consume(ctx context.Context) <-chan struct{} {
+	op := "Consumer.Consume"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("task", h.task),
+	)
+
+	done := make(chan struct{})
+
+	log.Info("consumer started")
+
+	h.running.Store(true)
+
+	go func() {
+		defer close(done)
+		defer h.running.Store(false)
+
+		var wg sync.WaitGroup
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("consumer stopped")
+				wg.Wait()
+				return
+
+			case d, ok := <-h.messages:
+				if !ok {
+					log.Info("consumer stopped, channel closed")
+					return
+				}
+
+				wg.Add(1)
+
+				go func(msg amqp091.Delivery) {
+					defer wg.Done()
+
+					idStr := string(msg.Body)
+					id, err := strconv.ParseInt(idStr, 10, 64)
+					if err != nil {
+						log.Error("failed to parse track ID", "error", err)
+
+						_ = msg.Nack(false, false)
+
+						return
+					}
+
+					msgCtx, span := tracing.Tracer().Start(
+						tracing.ExtractAMQP(ctx, msg.Headers),
+						"Consumer.process",
+						trace.WithSpanKind(trace.SpanKindConsumer),
+						trace.WithAttributes(attribute.Int64("track_id", id), attribute.String("task", h.task)),
+					)
+					defer span.End()
+
+					log := log.With(slog.String("trace_id", tracing.TraceID(msgCtx)))
+
+					log.Info("processing track", "track_id", id)
+
+					start := time.Now()
+					err = h.handle(msgCtx, id)
+					metrics.JobDuration.WithLabelValues(h.task, metrics.Outcome(err)).Observe(time.Since(start).Seconds())
+					tracing.RecordError(span, err)
+
+					if err != nil {
+						requeue := !h.isPermanent(err)
+						log.Error("failed to process track", "track_id", id, "requeue", requeue, "error", err)
+
+						_ = msg.Nack(false, requeue)
+
+						return
+					}
+
+					_ = msg.Ack(false)
+
+					log.Info("finished processing track", "track_id", id)
+				}(d)
+			}
+		}
+	}()
+
+	return done
+}
+
+func (h *Consumer) isPermanent(err error) bool {
+	for _, target := range h.permanent {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reports whether the consume loop is still receiving deliveries.
+func (h *Consumer) Check(_ context.Context) error {
+	if !h.running.Load() {
+		return ErrNotRunning
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package authz holds every authorization policy of the service.
+// Handlers and services must ask this package instead of comparing roles themselves.
+package authz
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+var roles = []string{
+	models.RoleListener,
+	models.RoleArtist,
+	models.RoleModerator,
+	models.RoleAdmin,
+}
+
+var scopes = []string{
+	models.ScopeTracksRead,
+	models.ScopeTracksWrite,
+	models.ScopeAdmin,
+}
+
+var visibilities = []string{
+	models.VisibilityPublic,
+	models.VisibilityUnlisted,
+	models.VisibilityPrivate,
+}
+
+func ValidRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(scopes, scope)
+}
+
+func ValidVisibility(visibility string) bool {
+	return slices.Contains(visibilities, visibility)
+}
+
+func HasRole(user models.User, allowed ...string) bool {
+	return slices.Contains(allowed, user.Role)
+}
+
+// HasScope reports whether the API key the request was made with grants the scope.
+// Interactive sessions are not limited by scopes.
+func HasScope(user models.User, scope string) bool {
+	if user.Scopes == nil {
+		return true
+	}
+	return slices.Contains(user.Scopes, scope)
+}
+
+// CanReadTracks only restricts API keys without the tracks:read scope, listings are public otherwise.
+func CanReadTracks(user models.User) error {
+	if HasScope(user, models.ScopeTracksRead) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanViewTrack decides whether the user may play the track without a share link.
+// Anonymous listeners are passed as the zero user. Hidden tracks are left to their owner and the moderation staff.
+func CanViewTrack(user models.User, track models.Track) error {
+	if track.Hidden {
+		if isOwner(user, track) || HasRole(user, models.RoleModerator, models.RoleAdmin) {
+			return nil
+		}
+		return ErrForbidden
+	}
+	if track.Visibility == models.VisibilityPublic || track.Visibility == "" {
+		return nil
+	}
+	if isOwner(user, track) || HasRole(user, models.RoleAdmin) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanUpload allows everyone except plain listeners to upload tracks.
+func CanUpload(user models.User) error {
+	if HasRole(user, models.RoleArtist, models.RoleModerator, models.RoleAdmin) && HasScope(user, models.ScopeTracksWrite) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanEditTrack allows only the owner of the track or an admin to change it.
+func CanEditTrack(user models.User, track models.Track) error {
+	if !HasScope(user, models.ScopeTracksWrite) {
+		return ErrForbidden
+	}
+	if isOwner(user, track) || HasRole(user, models.RoleAdmin) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanDeleteTrack allows only the owner of the track or an admin to delete it.
+func CanDeleteTrack(user models.User, track models.Track) error {
+	return CanEditTrack(user, track)
+}
+
+// CanShareTrack allows the owner of the track or an admin to change its visibility and manage share links.
+func CanShareTrack(user models.User, track models.Track) error {
+	return CanEditTrack(user, track)
+}
+
+// CanEditLyrics allows only the owner of the track to change its lyrics.
+func CanEditLyrics(user models.User, track models.Track) error {
+	if HasScope(user, models.ScopeTracksWrite) && isOwner(user, track) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanDownloadTrack allows the owner of the track or an admin to download it in any format, the original included.
+// Other listeners may download tracks they can view in the formats of their plan.
+func CanDownloadTrack(user models.User, track models.Track) error {
+	if !HasScope(user, models.ScopeTracksRead) {
+		return ErrForbidden
+	}
+	if isOwner(user, track) || HasRole(user, models.RoleAdmin) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanHideTrack allows moderators and admins to hide any track from listings.
+func CanHideTrack(user models.User) error {
+	if HasRole(user, models.RoleModerator, models.RoleAdmin) && HasScope(user, models.ScopeTracksWrite) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanAdminister guards operational endpoints such as reprocessing and role management.
+func CanAdminister(user models.User) error {
+	if HasRole(user, models.RoleAdmin) && HasScope(user, models.ScopeAdmin) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanManageAPIKeys requires an interactive session, so a leaked key can't be used to mint new ones.
+func CanManageAPIKeys(user models.User) error {
+	if user.Scopes == nil && user.APIKeyID == 0 {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanGrantScope prevents users from issuing keys with more power than they have themselves.
+func CanGrantScope(user models.User, scope string) error {
+	switch scope {
+	case models.ScopeAdmin:
+		return CanAdminister(user)
+	case models.ScopeTracksWrite:
+		if HasRole(user, models.RoleListener) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+func isOwner(user models.User, track models.Track) bool {
+	return track.UploadedBy != nil && *track.UploadedBy == user.ID
+}
@@ -7,7 +7,12 @@ import (
 )
 
 func (r *RabbitMQ) GetTrackTaskStream() (<-chan amqp.Delivery, error) {
-	const op = "broker.GetTrackTaskStream"
+	return r.GetTaskStream(AudioTasksQueue)
+}
+
+// GetTaskStream starts consuming the queue with manual acknowledgements, one unacked task at a time.
+func (r *RabbitMQ) GetTaskStream(queue string) (<-chan amqp.Delivery, error) {
+	const op = "broker.GetTaskStream"
 
 	err := r.Channel.Qos(1, 0, false)
 	if err != nil {
@@ -15,7 +20,7 @@ func (r *RabbitMQ) GetTrackTaskStream() (<-chan amqp.Delivery, error) {
 	}
 
 	msgs, err := r.Channel.Consume(
-		AudioTasksQueue,
+		queue,
 		"",
 		false,
 		false,
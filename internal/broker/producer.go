@@ -4,20 +4,44 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 func (r *RabbitMQ) SendTrackTask(ctx context.Context, trackId string) error {
-	const op = "broker.SendTrackTask"
+	return r.sendTask(ctx, AudioTasksQueue, trackId)
+}
+
+// SendWaveformTask queues the computation of the waveform peaks of a track.
+func (r *RabbitMQ) SendWaveformTask(ctx context.Context, trackId string) error {
+	return r.sendTask(ctx, WaveformTasksQueue, trackId)
+}
+
+// SendPreviewTask queues the cut of the preview rendition of a track.
+func (r *RabbitMQ) SendPreviewTask(ctx context.Context, trackId string) error {
+	return r.sendTask(ctx, PreviewTasksQueue, trackId)
+}
+
+// SendDownloadTask queues the transcode of a track to a download format.
+func (r *RabbitMQ) SendDownloadTask(ctx context.Context, trackId string, format string) error {
+	return r.sendTask(ctx, DownloadTasksQueue(format), trackId)
+}
+
+func (r *RabbitMQ) sendTask(ctx context.Context, queue, trackId string) error {
+	const op = "broker.sendTask"
+
+	headers := amqp.Table{}
+	tracing.InjectAMQP(ctx, headers)
 
 	err := r.Channel.PublishWithContext(
 		ctx,
-		"",              // exchange
-		AudioTasksQueue, // routing key
-		false,           // mandatory
-		false,           // immediate
+		"",    // exchange
+		queue, // routing key
+		false, // mandatory
+		false, // immediate
 		amqp.Publishing{
 			ContentType: "text/plain",
+			Headers:     headers,
 			Body:        []byte(trackId),
 		},
 	)
@@ -1,13 +1,34 @@
 package broker
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-const AudioTasksQueue = "audio_tasks"
+const (
+	AudioTasksQueue    = "audio_tasks"
+	WaveformTasksQueue = "waveform_tasks"
+	PreviewTasksQueue  = "preview_tasks"
+)
+
+// DownloadTasksQueue returns the queue of the transcodes to format, every format has its own consumer.
+func DownloadTasksQueue(format string) string {
+	return "download_" + format + "_tasks"
+}
+
+// TaskQueues returns every queue tasks are sent to.
+func TaskQueues() []string {
+	queues := []string{AudioTasksQueue, WaveformTasksQueue, PreviewTasksQueue}
+	for _, format := range media.TranscodeFormats {
+		queues = append(queues, DownloadTasksQueue(format))
+	}
+	return queues
+}
 
 type RabbitMQ struct {
 	Conn    *amqp.Connection
@@ -56,14 +77,113 @@ func (r *RabbitMQ) Close() {
 	}
 }
 
-func (r *RabbitMQ) initQueue() error {
-	_, err := r.Channel.QueueDeclare(
-		AudioTasksQueue,
+// Check reports whether the connection and the channel are still open.
+func (r *RabbitMQ) Check(_ context.Context) error {
+	const op = "broker.Check"
+
+	if r.Conn == nil || r.Conn.IsClosed() {
+		return fmt.Errorf("%s: connection is closed", op)
+	}
+	if r.Channel == nil || r.Channel.IsClosed() {
+		return fmt.Errorf("%s: channel is closed", op)
+	}
+
+	return nil
+}
+
+// QueueDepth returns the number of messages ready for delivery in the queue.
+func (r *RabbitMQ) QueueDepth(queue string) (int, error) {
+	const op = "broker.QueueDepth"
+
+	q, err := r.Channel.QueueDeclarePassive(
+		queue,
+		true,  // durable
+		false, // autoDelete
+		false, // exclusive
+		false, // noWait
+		nil,   // args
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: can't inspect queue: %w", op, err)
+	}
+
+	return q.Messages, nil
+}
+
+// QueueState describes a queue and the messages at its head.
+type QueueState struct {
+	Name      string
+	Messages  int
+	Consumers int
+	Head      []QueuedMessage
+}
+
+type QueuedMessage struct {
+	Body        string
+	Redelivered bool
+}
+
+// InspectQueue returns the queue counters and up to peek messages from its head.
+// Peeked messages are requeued, so they are marked redelivered afterwards.
+func (r *RabbitMQ) InspectQueue(queue string, peek int) (QueueState, error) {
+	const op = "broker.InspectQueue"
+
+	q, err := r.Channel.QueueDeclarePassive(
+		queue,
 		true,  // durable
 		false, // autoDelete
 		false, // exclusive
 		false, // noWait
 		nil,   // args
 	)
-	return err
+	if err != nil {
+		return QueueState{}, fmt.Errorf("%s: can't inspect queue: %w", op, err)
+	}
+
+	state := QueueState{
+		Name:      q.Name,
+		Messages:  q.Messages,
+		Consumers: q.Consumers,
+	}
+
+	var lastTag uint64
+	for range peek {
+		d, ok, err := r.Channel.Get(queue, false)
+		if err != nil {
+			return state, fmt.Errorf("%s: can't get message: %w", op, err)
+		}
+		if !ok {
+			break
+		}
+		lastTag = d.DeliveryTag
+		state.Head = append(state.Head, QueuedMessage{
+			Body:        string(d.Body),
+			Redelivered: d.Redelivered,
+		})
+	}
+
+	if lastTag != 0 {
+		if err := r.Channel.Nack(lastTag, true, true); err != nil {
+			return state, fmt.Errorf("%s: can't requeue messages: %w", op, err)
+		}
+	}
+
+	return state, nil
+}
+
+func (r *RabbitMQ) initQueue() error {
+	for _, queue := range TaskQueues() {
+		_, err := r.Channel.QueueDeclare(
+			queue,
+			true,  // durable
+			false, // autoDelete
+			false, // exclusive
+			false, // noWait
+			nil,   // args
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
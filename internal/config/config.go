@@ -5,17 +5,31 @@ import (
 	"os"
 	"time"
 
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Env          string       `yaml:"env"`
-	HTTPServer   HTTPServer   `yaml:"http_server"`
-	PostgreSQL   PostgreSQL   `yaml:"postgresql"`
-	MinIOClient  MinIOClient  `yaml:"minio_client"`
-	MinioStorage MinioStorage `yaml:"minio_storage"`
-	RabbitMQ     RabbitMQ     `yaml:"rabbitmq"`
+	Env           string        `yaml:"env"`
+	HTTPServer    HTTPServer    `yaml:"http_server"`
+	PostgreSQL    PostgreSQL    `yaml:"postgresql"`
+	MinIOClient   MinIOClient   `yaml:"minio_client"`
+	MinioStorage  MinioStorage  `yaml:"minio_storage"`
+	RabbitMQ      RabbitMQ      `yaml:"rabbitmq"`
+	StreamCache   StreamCache   `yaml:"stream_cache"`
+	StreamSigning StreamSigning `yaml:"stream_signing"`
+	HLS           HLS           `yaml:"hls"`
+	Waveform      Waveform      `yaml:"waveform"`
+	Cover         Cover         `yaml:"cover"`
+	Preview       Preview       `yaml:"preview"`
+	Download      Download      `yaml:"download"`
+	Auth          Auth          `yaml:"auth"`
+	RateLimit     RateLimit     `yaml:"rate_limit"`
+	Quotas        Quotas        `yaml:"quotas"`
+	Metrics       Metrics       `yaml:"metrics"`
+	Health        Health        `yaml:"health"`
+	Tracing       Tracing       `yaml:"tracing"`
 }
 
 type HTTPServer struct {
@@ -25,12 +39,15 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout"`
 }
 
+// PostgreSQL configures the database. With AutoMigrate the service applies the embedded
+// migrations on start, otherwise they are applied with the migrate subcommand.
 type PostgreSQL struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	DBName   string `yaml:"db_name"`
-	UserName string `yaml:"user_name"`
-	Password string `yaml:"password" env:"PGSQL_PASSWORD" env_required:"true"`
+	Host        string `yaml:"host"`
+	Port        int    `yaml:"port"`
+	DBName      string `yaml:"db_name"`
+	UserName    string `yaml:"user_name"`
+	Password    string `yaml:"password" env:"PGSQL_PASSWORD" env_required:"true"`
+	AutoMigrate bool   `yaml:"auto_migrate" env:"PGSQL_AUTO_MIGRATE"`
 }
 
 type MinIOClient struct {
@@ -52,6 +69,179 @@ type RabbitMQ struct {
 	Password string `yaml:"password" env:"RABBITMQ_PASSWORD" env_required:"true"`
 }
 
+type StreamCache struct {
+	Enabled       bool          `yaml:"enabled"`
+	MemoryLimitMB int64         `yaml:"memory_limit_mb" env-default:"256"`
+	MaxObjectMB   int64         `yaml:"max_object_mb" env-default:"8"`
+	TTL           time.Duration `yaml:"ttl"`
+	PlaylistTTL   time.Duration `yaml:"playlist_ttl" env-default:"10s"`
+	DiskDir       string        `yaml:"disk_dir"`
+	DiskLimitMB   int64         `yaml:"disk_limit_mb"`
+}
+
+// StreamSigning configures signed stream URLs.
+// Keys is a comma-separated list of id:secret pairs, new tokens are signed with ActiveKey.
+type StreamSigning struct {
+	Enabled   bool          `yaml:"enabled"`
+	TTL       time.Duration `yaml:"ttl" env-default:"6h"`
+	ActiveKey string        `yaml:"active_key"`
+	Keys      string        `yaml:"keys" env:"STREAM_SIGNING_KEYS"`
+}
+
+// HLS configures the renditions. SegmentType is "aac" for packed audio or "fmp4" for CMAF fragments.
+// DASH writes an MPD manifest next to every playlist, it needs fmp4 segments.
+type HLS struct {
+	SegmentSeconds int           `yaml:"segment_seconds" env-default:"4"`
+	SegmentType    string        `yaml:"segment_type" env-default:"aac"`
+	DASH           bool          `yaml:"dash"`
+	Encryption     HLSEncryption `yaml:"encryption"`
+	Loudness       HLSLoudness   `yaml:"loudness"`
+	Lossless       HLSLossless   `yaml:"lossless"`
+}
+
+// HLSLossless configures the lossless rendition of lossless sources. Codec is "flac" or "alac",
+// segments are always fMP4. Which users may stream it is decided by the quota plans,
+// every logged-in listener may when quotas are disabled.
+type HLSLossless struct {
+	Enabled bool   `yaml:"enabled"`
+	Codec   string `yaml:"codec" env-default:"flac"`
+}
+
+// HLSEncryption configures AES-128 segment encryption.
+// KeyEncryptionKey is a hex-encoded AES key used to wrap per-track keys at rest, KeyRotation is the number
+// of segments per key (0 means one key per track).
+type HLSEncryption struct {
+	Enabled          bool   `yaml:"enabled"`
+	KeyRotation      int    `yaml:"key_rotation"`
+	KeyEncryptionKey string `yaml:"key_encryption_key" env:"HLS_KEY_ENCRYPTION_KEY"`
+}
+
+// Waveform configures the peaks computed by the worker after segmentation.
+// Resolutions are samples per pixel at SampleRate, the service serves only the configured ones.
+type Waveform struct {
+	Enabled     bool  `yaml:"enabled"`
+	SampleRate  int   `yaml:"sample_rate" env-default:"22050"`
+	Resolutions []int `yaml:"resolutions" env-default:"256,1024,4096"`
+}
+
+// Cover configures the square cover variants the worker stores for every track. Sizes are in pixels,
+// Quality applies to the JPEG variants, requests for other sizes get the next larger variant.
+type Cover struct {
+	Enabled bool  `yaml:"enabled"`
+	Sizes   []int `yaml:"sizes" env-default:"64,300,1000"`
+	Quality int   `yaml:"quality" env-default:"85"`
+}
+
+// Preview configures the clips cut by the worker after segmentation. When enabled, anonymous listeners
+// only get the clip of public tracks and need to log in or hold a share link for the full track.
+type Preview struct {
+	Enabled bool          `yaml:"enabled"`
+	Length  time.Duration `yaml:"length" env-default:"30s"`
+}
+
+// Download configures the downloads of original and transcoded files. Owners download their tracks
+// in any format, other listeners in the formats of their plan, so they can't download anything while
+// quotas are disabled. Transcodes are made by the worker and cached.
+type Download struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// HLSLoudness configures the EBU R128 loudness measurement. With Normalize the renditions are normalized
+// to TargetLUFS, otherwise the gain to reach it is exposed to clients. TruePeak (dBTP) and Range (LU)
+// bound the normalization.
+type HLSLoudness struct {
+	Enabled    bool    `yaml:"enabled"`
+	Normalize  bool    `yaml:"normalize"`
+	TargetLUFS float64 `yaml:"target_lufs" env-default:"-14"`
+	TruePeak   float64 `yaml:"true_peak" env-default:"-1"`
+	Range      float64 `yaml:"range" env-default:"11"`
+}
+
+type Auth struct {
+	JWTSecret       string        `yaml:"jwt_secret" env:"JWT_SECRET"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	SecureCookies   bool          `yaml:"secure_cookies"`
+	DefaultRole     string        `yaml:"default_role" env-default:"listener"`
+}
+
+// RateLimit configures token-bucket limits per route group.
+// Store is either "memory" or "postgres", the latter shares buckets between replicas.
+// X-Forwarded-For is only trusted for requests coming from TrustedProxies (CIDRs or addresses).
+type RateLimit struct {
+	Enabled        bool          `yaml:"enabled"`
+	Store          string        `yaml:"store" env-default:"memory"`
+	TrustedProxies []string      `yaml:"trusted_proxies"`
+	Upload         RateLimitRule `yaml:"upload"`
+	List           RateLimitRule `yaml:"list"`
+	Stream         RateLimitRule `yaml:"stream"`
+}
+
+// RateLimitRule allows Burst requests that refill evenly over Period. A zero Burst disables the rule.
+type RateLimitRule struct {
+	Burst  int           `yaml:"burst"`
+	Period time.Duration `yaml:"period"`
+}
+
+// Quotas configures storage plans. Users on a plan that is not configured get the limits of DefaultPlan,
+// per-user overrides are set by admins.
+type Quotas struct {
+	Enabled     bool            `yaml:"enabled"`
+	DefaultPlan string          `yaml:"default_plan" env-default:"free"`
+	Plans       map[string]Plan `yaml:"plans"`
+}
+
+// Plan limits are unlimited when zero. Lossless plans may stream the lossless renditions,
+// Downloads are the formats (mp3, flac, opus) the plan may download tracks of other users in.
+type Plan struct {
+	MaxStorageMB int64         `yaml:"max_storage_mb"`
+	MaxTracks    int           `yaml:"max_tracks"`
+	MaxFileMB    int64         `yaml:"max_file_mb"`
+	MaxDuration  time.Duration `yaml:"max_duration"`
+	Lossless     bool          `yaml:"lossless"`
+	Downloads    []string      `yaml:"downloads"`
+}
+
+// PlanLimits converts the configured plans to quota limits.
+func (q Quotas) PlanLimits() map[string]models.Quota {
+	plans := make(map[string]models.Quota, len(q.Plans))
+	for name, plan := range q.Plans {
+		plans[name] = models.Quota{
+			MaxBytes:     plan.MaxStorageMB << 20,
+			MaxTracks:    plan.MaxTracks,
+			MaxFileBytes: plan.MaxFileMB << 20,
+			MaxDuration:  plan.MaxDuration,
+			Lossless:     plan.Lossless,
+			Downloads:    plan.Downloads,
+		}
+	}
+	return plans
+}
+
+// Metrics configures Prometheus metrics. Both binaries serve them at /metrics,
+// the worker on the listener configured in Health.
+type Metrics struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// Health configures the /healthz and /readyz probes. Timeout bounds a whole readiness run.
+// The service serves the probes on its HTTP server, the worker has no HTTP server
+// and starts a separate listener on Host and Port.
+type Health struct {
+	Timeout time.Duration `yaml:"timeout" env-default:"3s"`
+	Host    string        `yaml:"host" env-default:"0.0.0.0"`
+	Port    int           `yaml:"port" env-default:"9091"`
+}
+
+// Tracing configures OpenTelemetry. Exporter is "none", "stdout" for local development
+// or "otlp" to send spans over OTLP/HTTP to Endpoint (host:port).
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
 func Load() *Config {
 	_ = godotenv.Load()
 
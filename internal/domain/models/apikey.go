@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	ScopeTracksRead  = "tracks:read"
+	ScopeTracksWrite = "tracks:write"
+	ScopeAdmin       = "admin"
+)
+
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Prefix     string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
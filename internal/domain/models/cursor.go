@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Cursor is the position of an item in a listing other than the track listing, the listing goes on after it.
+// Listings ordered by creation time, newest first, set CreatedAt, the ones ordered by name set Name. ID breaks ties.
+type Cursor struct {
+	CreatedAt time.Time
+	Name      string
+	ID        int64
+}
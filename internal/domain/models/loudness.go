@@ -0,0 +1,11 @@
+package models
+
+// Loudness is the EBU R128 measurement of a track's original file.
+// Gain is the adjustment in dB a client applies to reach the target loudness,
+// it is 0 when the renditions are already normalized.
+type Loudness struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+	Gain       float64
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	LyricsPlain = "plain"
+	LyricsLRC   = "lrc"
+)
+
+// Lyrics of a track as uploaded by its owner. Format is LyricsPlain or LyricsLRC,
+// Language is a BCP 47 tag and may be empty.
+type Lyrics struct {
+	TrackID   int64
+	Format    string
+	Text      string
+	Language  string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const PlanFree = "free"
+
+// Quota limits what a user may store. Zero values mean unlimited.
+// Lossless entitles the user to the lossless renditions, Downloads lists the formats the user
+// may download tracks of other users in.
+type Quota struct {
+	MaxBytes     int64
+	MaxTracks    int
+	MaxFileBytes int64
+	MaxDuration  time.Duration
+	Lossless     bool
+	Downloads    []string
+}
+
+// QuotaOverride replaces single limits of the user's plan. Nil fields keep the plan value.
+type QuotaOverride struct {
+	MaxBytes     *int64
+	MaxTracks    *int
+	MaxFileBytes *int64
+	MaxDuration  *time.Duration
+}
+
+type StorageUsage struct {
+	Bytes  int64
+	Tracks int
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ShareLink is a link granting access to a track. LastPlayedAt is when a play was last counted.
+type ShareLink struct {
+	ID           int64
+	TrackID      int64
+	CreatedBy    *int64
+	ExpiresAt    *time.Time
+	MaxPlays     *int
+	Plays        int
+	LastPlayedAt *time.Time
+	RevokedAt    *time.Time
+	CreatedAt    time.Time
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Orders of the track listing.
+const (
+	SortNewest     = "newest"
+	SortTitle      = "title"
+	SortPopularity = "popularity"
+)
+
+// Genre is a node of the genre tree managed by admins, top-level genres have no ParentID.
+type Genre struct {
+	ID        int64
+	ParentID  *int64
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+}
+
+// TrackFilter narrows the track listing. Genre matches the genre and all its subgenres, every tag in Tags
+// must be set on the track and ArtistID is the uploader. Zero values don't filter.
+// The page starts after the After position when it is set, Offset is ignored then.
+type TrackFilter struct {
+	ViewerID    int64
+	Genre       string
+	Tags        []string
+	ArtistID    int64
+	DurationMin *time.Duration
+	DurationMax *time.Duration
+	Sort        string
+	Limit       int
+	Offset      int
+	After       *TrackCursor
+}
+
+// Facet is the number of tracks matching a filter that carry a genre or a tag. Name is only set for genres.
+type Facet struct {
+	Value string
+	Name  string
+	Count int
+}
+
+// TrackFacets summarizes all tracks matching a filter, not only the listed page.
+type TrackFacets struct {
+	Total  int
+	Genres []Facet
+	Tags   []Facet
+}
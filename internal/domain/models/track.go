@@ -2,18 +2,79 @@ package models
 
 import "time"
 
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Track is a stored track. PreviewStart is the preview window picked by the owner, nil means the middle
+// of the track, PreviewPrefix is set once the preview rendition is stored. LosslessPrefix is only set
+// for lossless sources. HasDASH tells whether the renditions come with an MPD manifest.
+// Duration is nil until the worker probed the original. GenreID is nil for tracks without a genre.
 type Track struct {
-	ID           int64
-	Title        string
-	CreatedAt    time.Time
-	OriginBucket string
-	OriginKey    string
-	HLSBucket    *string
-	HLSPrefix    *string
+	ID             int64
+	Title          string
+	CreatedAt      time.Time
+	OriginBucket   string
+	OriginKey      string
+	HLSBucket      *string
+	HLSPrefix      *string
+	UploadedBy     *int64
+	Hidden         bool
+	Visibility     string
+	Status         string
+	SizeBytes      int64
+	GainDB         *float64
+	HasCover       bool
+	PreviewStart   *time.Duration
+	PreviewPrefix  *string
+	LosslessPrefix *string
+	HasDASH        bool
+	Duration       *time.Duration
+	GenreID        *int64
 }
 
+// TrackListItem is a track as listed. Genre is the slug of its genre, ArtistID its uploader.
 type TrackListItem struct {
+	ID          int64
+	Title       string
+	CreatedAt   time.Time
+	GainDB      *float64
+	HasCover    bool
+	HasPreview  bool
+	HasLossless bool
+	HasDASH     bool
+	ArtistID    *int64
+	Genre       *string
+	Tags        []string
+	Duration    *time.Duration
+	Plays       int64
+}
+
+// TrackCursor is the position of a track in a listing order, the listing goes on after it.
+// CreatedAt and ID are always set, Title and Plays are only used by their orders.
+type TrackCursor struct {
+	Sort      string
+	CreatedAt time.Time
 	ID        int64
 	Title     string
-	CreatedAt time.Time
+	Plays     int64
+}
+
+// TrackPage is a page of the track listing. Next is nil on the last page, Facets is nil on cursor
+// pages after the first one since they don't change from page to page.
+type TrackPage struct {
+	Items  []TrackListItem
+	Next   *TrackCursor
+	Facets *TrackFacets
+}
+
+// TrackKey is an HLS encryption key. Run is the processing run whose rendition the key encrypts,
+// empty for keys of renditions published before renditions were kept per run.
+type TrackKey struct {
+	TrackID  int64
+	Run      string
+	Index    int
+	Material []byte
 }
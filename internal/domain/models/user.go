@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	RoleListener  = "listener"
+	RoleArtist    = "artist"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+type User struct {
+	ID           int64
+	Email        string
+	Role         string
+	PasswordHash []byte
+	CreatedAt    time.Time
+
+	// Scopes limits what the request may do when it was authenticated by an API key.
+	// It is nil for interactive sessions, which are limited by Role only.
+	Scopes []string
+	// APIKeyID is set when the request was authenticated by an API key.
+	APIKeyID int64
+}
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        time.Time
+	RefreshExpiresAt time.Time
+}
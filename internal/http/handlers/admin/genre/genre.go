@@ -0,0 +1,203 @@
+package genre
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/genres"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Request creates or replaces a genre. A genre without ParentID is top-level.
+type Request struct {
+	ParentID *int64 `json:"parent_id,omitempty" validate:"omitempty,gt=0"`
+	Slug     string `json:"slug" validate:"required,max=64"`
+	Name     string `json:"name" validate:"required,max=100"`
+}
+
+type Response struct {
+	response.Response
+	ID int64 `json:"id"`
+}
+
+type GenreCreator interface {
+	CreateGenre(ctx context.Context, actor models.User, genre models.Genre) (int64, error)
+}
+
+type GenreUpdater interface {
+	UpdateGenre(ctx context.Context, actor models.User, genre models.Genre) error
+}
+
+type GenreDeleter interface {
+	DeleteGenre(ctx context.Context, actor models.User, id int64) error
+}
+
+func Create(log *slog.Logger, creator GenreCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.genre.Create"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		actor, _ := authmw.UserFromContext(r.Context())
+
+		req, ok := decodeRequest(w, r, log)
+		if !ok {
+			return
+		}
+
+		id, err := creator.CreateGenre(r.Context(), actor, models.Genre{
+			ParentID: req.ParentID,
+			Slug:     req.Slug,
+			Name:     req.Name,
+		})
+		if err != nil {
+			writeError(w, r, log, err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, Response{ID: id})
+	}
+}
+
+// Update renames or moves a genre, its tracks keep it.
+func Update(log *slog.Logger, updater GenreUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.genre.Update"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		actor, _ := authmw.UserFromContext(r.Context())
+
+		id, ok := parseGenreID(w, r)
+		if !ok {
+			return
+		}
+
+		req, ok := decodeRequest(w, r, log)
+		if !ok {
+			return
+		}
+
+		err := updater.UpdateGenre(r.Context(), actor, models.Genre{
+			ID:       id,
+			ParentID: req.ParentID,
+			Slug:     req.Slug,
+			Name:     req.Name,
+		})
+		if err != nil {
+			writeError(w, r, log, err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{ID: id})
+	}
+}
+
+// Delete removes a genre without subgenres, its tracks are left without a genre.
+func Delete(log *slog.Logger, deleter GenreDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.genre.Delete"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		actor, _ := authmw.UserFromContext(r.Context())
+
+		id, ok := parseGenreID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := deleter.DeleteGenre(r.Context(), actor, id); err != nil {
+			writeError(w, r, log, err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response.Response{})
+	}
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, log *slog.Logger) (Request, bool) {
+	var req Request
+
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		log.Error("failed to decode request body", logger.Err(err))
+
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, response.Error("failed to decode request"))
+
+		return req, false
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", logger.Err(err))
+
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationError(validateErr))
+
+		return req, false
+	}
+
+	return req, true
+}
+
+func parseGenreID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chigo.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid genre id"))
+
+		return 0, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, genres.ErrGenreNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, response.Error("genre not found"))
+	case errors.Is(err, genres.ErrParentNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, response.Error("parent genre not found"))
+	case errors.Is(err, genres.ErrInvalidGenre):
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid genre: slug must be lowercase letters and digits separated by dashes and a genre can't be moved under itself"))
+	case errors.Is(err, genres.ErrGenreExists):
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, response.Error("genre already exists"))
+	case errors.Is(err, genres.ErrGenreInUse):
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, response.Error("genre has subgenres"))
+	case errors.Is(err, authz.ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+		render.JSON(w, r, response.Error("forbidden"))
+	default:
+		log.Error("failed to change genre", logger.Err(err))
+
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to change genre"))
+	}
+}
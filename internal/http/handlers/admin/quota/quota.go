@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/quota"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Request sets the plan of the user. Limits left out are taken from the plan.
+type Request struct {
+	Plan               string `json:"plan" validate:"required"`
+	MaxBytes           *int64 `json:"max_bytes,omitempty" validate:"omitempty,min=0"`
+	MaxTracks          *int   `json:"max_tracks,omitempty" validate:"omitempty,min=0"`
+	MaxFileBytes       *int64 `json:"max_file_bytes,omitempty" validate:"omitempty,min=0"`
+	MaxDurationSeconds *int   `json:"max_duration_seconds,omitempty" validate:"omitempty,min=0"`
+}
+
+type QuotaSetter interface {
+	SetQuota(ctx context.Context, actor models.User, userID int64, plan string, override models.QuotaOverride) error
+}
+
+func New(log *slog.Logger, setter QuotaSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.quota.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		actor, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		userID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || userID <= 0 {
+			log.Error("invalid user id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid user id"))
+
+			return
+		}
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		override := models.QuotaOverride{
+			MaxBytes:     req.MaxBytes,
+			MaxTracks:    req.MaxTracks,
+			MaxFileBytes: req.MaxFileBytes,
+		}
+		if req.MaxDurationSeconds != nil {
+			d := time.Duration(*req.MaxDurationSeconds) * time.Second
+			override.MaxDuration = &d
+		}
+
+		err = setter.SetQuota(r.Context(), actor, userID, req.Plan, override)
+		switch {
+		case errors.Is(err, quota.ErrUserNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		case errors.Is(err, quota.ErrUnknownPlan):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unknown plan"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to set quota", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to set quota"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response.Response{})
+	}
+}
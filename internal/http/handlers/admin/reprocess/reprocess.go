@@ -0,0 +1,69 @@
+package reprocess
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/manage"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type TrackReprocessor interface {
+	ReprocessTrack(ctx context.Context, user models.User, id int64) error
+}
+
+func New(log *slog.Logger, reprocessor TrackReprocessor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.reprocess.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		trackID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || trackID <= 0 {
+			log.Error("invalid track id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		err = reprocessor.ReprocessTrack(r.Context(), user, trackID)
+		switch {
+		case errors.Is(err, manage.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to reprocess track", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to reprocess track"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		render.JSON(w, r, response.Response{})
+	}
+}
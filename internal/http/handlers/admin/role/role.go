@@ -0,0 +1,100 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/auth"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type RoleSetter interface {
+	SetRole(ctx context.Context, actor models.User, userID int64, role string) error
+}
+
+func New(log *slog.Logger, setter RoleSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.role.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		actor, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		userID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || userID <= 0 {
+			log.Error("invalid user id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid user id"))
+
+			return
+		}
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		err = setter.SetRole(r.Context(), actor, userID, req.Role)
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		case errors.Is(err, auth.ErrInvalidRole):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid role"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to set role", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to set role"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response.Response{})
+	}
+}
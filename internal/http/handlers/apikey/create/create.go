@@ -0,0 +1,99 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/apikeys"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+}
+
+type Response struct {
+	response.Response
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type KeyCreator interface {
+	CreateKey(ctx context.Context, user models.User, name string, scopes []string) (models.APIKey, string, error)
+}
+
+func New(log *slog.Logger, creator KeyCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		key, raw, err := creator.CreateKey(r.Context(), user, req.Name, req.Scopes)
+		switch {
+		case errors.Is(err, apikeys.ErrInvalidScopes):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid scopes"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to create api key", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to create api key"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, Response{
+			ID:        key.ID,
+			Name:      key.Name,
+			Key:       raw,
+			Scopes:    key.Scopes,
+			CreatedAt: key.CreatedAt,
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/cursor"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/go-chi/render"
+)
+
+// Response is a page of keys, newest first. NextCursor fetches the next page and is empty on the last one.
+type Response struct {
+	response.Response
+	Items      []KeyResponse `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type KeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type KeyLister interface {
+	ListKeys(ctx context.Context, user models.User, limit int, after *models.Cursor) ([]models.APIKey, *models.Cursor, error)
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// New lists the keys of the user, pages are fetched with the limit and cursor query parameters.
+func New(log *slog.Logger, lister KeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		limit, after, err := cursor.ParsePage(r.URL.Query(), defaultLimit, maxLimit)
+		if err != nil {
+			log.Error("invalid page", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+
+		keys, next, err := lister.ListKeys(r.Context(), user, limit, after)
+		if errors.Is(err, authz.ErrForbidden) {
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to list api keys", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to list api keys"))
+
+			return
+		}
+
+		items := make([]KeyResponse, len(keys))
+		for i, k := range keys {
+			items[i] = KeyResponse{
+				ID:         k.ID,
+				Name:       k.Name,
+				Prefix:     k.Prefix,
+				Scopes:     k.Scopes,
+				CreatedAt:  k.CreatedAt,
+				LastUsedAt: k.LastUsedAt,
+				RevokedAt:  k.RevokedAt,
+			}
+		}
+
+		resp := Response{Items: items}
+		if next != nil {
+			resp.NextCursor = cursor.Encode(*next)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,68 @@
+package revoke
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/apikeys"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type KeyRevoker interface {
+	RevokeKey(ctx context.Context, user models.User, id int64) error
+}
+
+func New(log *slog.Logger, revoker KeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.revoke.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		keyID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || keyID <= 0 {
+			log.Error("invalid key id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid key id"))
+
+			return
+		}
+
+		err = revoker.RevokeKey(r.Context(), user, keyID)
+		switch {
+		case errors.Is(err, apikeys.ErrKeyNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("api key not found"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to revoke api key", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to revoke api key"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,91 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/auth"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Response struct {
+	response.Response
+	AccessToken  string    `json:"access_token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+type UserLoginer interface {
+	Login(ctx context.Context, email string, password string) (models.TokenPair, error)
+}
+
+func New(log *slog.Logger, loginer UserLoginer, secureCookies bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.login.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		pair, err := loginer.Login(r.Context(), req.Email, req.Password)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			log.Info("invalid credentials")
+
+			w.WriteHeader(http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid email or password"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to login", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to login"))
+
+			return
+		}
+
+		authmw.SetTokenCookies(w, pair, secureCookies)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{
+			AccessToken:  pair.AccessToken,
+			RefreshToken: pair.RefreshToken,
+			ExpiresAt:    pair.ExpiresAt,
+		})
+	}
+}
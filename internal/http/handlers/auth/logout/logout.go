@@ -0,0 +1,65 @@
+package logout
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UserLogouter interface {
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+func New(log *slog.Logger, logouter UserLogouter, secureCookies bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.logout.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		var req Request
+
+		if r.ContentLength != 0 {
+			if err := render.DecodeJSON(r.Body, &req); err != nil {
+				log.Error("failed to decode request body", logger.Err(err))
+
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, response.Error("failed to decode request"))
+
+				return
+			}
+		}
+
+		if req.RefreshToken == "" {
+			if c, err := r.Cookie(authmw.RefreshTokenCookie); err == nil {
+				req.RefreshToken = c.Value
+			}
+		}
+
+		if req.RefreshToken != "" {
+			if err := logouter.Logout(r.Context(), req.RefreshToken); err != nil {
+				log.Error("failed to logout", logger.Err(err))
+
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("failed to logout"))
+
+				return
+			}
+		}
+
+		authmw.ClearTokenCookies(w, secureCookies)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response.Response{})
+	}
+}
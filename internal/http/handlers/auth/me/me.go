@@ -0,0 +1,28 @@
+package me
+
+import (
+	"net/http"
+
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	response.Response
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+// New returns the authenticated user. It must be mounted behind auth.Required.
+func New() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := authmw.UserFromContext(r.Context())
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{
+			ID:    user.ID,
+			Email: user.Email,
+		})
+	}
+}
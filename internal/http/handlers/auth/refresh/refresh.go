@@ -0,0 +1,96 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/auth"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type Response struct {
+	response.Response
+	AccessToken  string    `json:"access_token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+type TokenRefresher interface {
+	Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error)
+}
+
+// New exchanges a refresh token taken from the JSON body or the refresh token cookie for a new token pair.
+func New(log *slog.Logger, refresher TokenRefresher, secureCookies bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.refresh.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		var req Request
+
+		if r.ContentLength != 0 {
+			if err := render.DecodeJSON(r.Body, &req); err != nil {
+				log.Error("failed to decode request body", logger.Err(err))
+
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, response.Error("failed to decode request"))
+
+				return
+			}
+		}
+
+		if req.RefreshToken == "" {
+			if c, err := r.Cookie(authmw.RefreshTokenCookie); err == nil {
+				req.RefreshToken = c.Value
+			}
+		}
+
+		if req.RefreshToken == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("missing refresh token"))
+
+			return
+		}
+
+		pair, err := refresher.Refresh(r.Context(), req.RefreshToken)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			log.Info("invalid refresh token")
+
+			authmw.ClearTokenCookies(w, secureCookies)
+			w.WriteHeader(http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid refresh token"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to refresh tokens", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to refresh tokens"))
+
+			return
+		}
+
+		authmw.SetTokenCookies(w, pair, secureCookies)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{
+			AccessToken:  pair.AccessToken,
+			RefreshToken: pair.RefreshToken,
+			ExpiresAt:    pair.ExpiresAt,
+		})
+	}
+}
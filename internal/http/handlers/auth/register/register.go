@@ -0,0 +1,80 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/auth"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type Response struct {
+	response.Response
+	ID int64 `json:"id,omitempty"`
+}
+
+type UserRegisterer interface {
+	Register(ctx context.Context, email string, password string) (int64, error)
+}
+
+func New(log *slog.Logger, registerer UserRegisterer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.register.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		id, err := registerer.Register(r.Context(), req.Email, req.Password)
+		if errors.Is(err, auth.ErrUserExists) {
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, response.Error("user already exists"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to register user", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to register user"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, Response{
+			ID: id,
+		})
+	}
+}
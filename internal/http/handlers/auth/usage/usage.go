@@ -0,0 +1,80 @@
+package usage
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/quota"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	response.Response
+	Plan   string       `json:"plan"`
+	Limits Limits       `json:"limits"`
+	Usage  StorageUsage `json:"usage"`
+}
+
+// Limits of zero are unlimited. Downloads are the formats tracks of other users may be downloaded in.
+type Limits struct {
+	MaxBytes           int64    `json:"max_bytes"`
+	MaxTracks          int      `json:"max_tracks"`
+	MaxFileBytes       int64    `json:"max_file_bytes"`
+	MaxDurationSeconds int64    `json:"max_duration_seconds"`
+	Lossless           bool     `json:"lossless"`
+	Downloads          []string `json:"downloads"`
+}
+
+type StorageUsage struct {
+	Bytes  int64 `json:"bytes"`
+	Tracks int   `json:"tracks"`
+}
+
+type UsageProvider interface {
+	Usage(ctx context.Context, user models.User) (quota.Report, error)
+}
+
+// New returns the storage usage and limits of the authenticated user. It must be mounted behind auth.Required.
+func New(log *slog.Logger, provider UsageProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.usage.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		report, err := provider.Usage(r.Context(), user)
+		if err != nil {
+			log.Error("failed to get usage", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get usage"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{
+			Plan: report.Plan,
+			Limits: Limits{
+				MaxBytes:           report.Limits.MaxBytes,
+				MaxTracks:          report.Limits.MaxTracks,
+				MaxFileBytes:       report.Limits.MaxFileBytes,
+				MaxDurationSeconds: int64(report.Limits.MaxDuration.Seconds()),
+				Lossless:           report.Limits.Lossless,
+				Downloads:          report.Limits.Downloads,
+			},
+			Usage: StorageUsage{
+				Bytes:  report.Usage.Bytes,
+				Tracks: report.Usage.Tracks,
+			},
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package list
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/cursor"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/go-chi/render"
+)
+
+// Response is a page of the genre tree listed flat by name, subgenres point to their parent with ParentID.
+// NextCursor fetches the next page and is empty on the last one.
+type Response struct {
+	response.Response
+	Items      []GenreResponse `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type GenreResponse struct {
+	ID       int64  `json:"id"`
+	ParentID *int64 `json:"parent_id,omitempty"`
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+}
+
+type GenreLister interface {
+	ListGenres(ctx context.Context, limit int, after *models.Cursor) ([]models.Genre, *models.Cursor, error)
+}
+
+const (
+	defaultLimit = 100
+	maxLimit     = 500
+)
+
+// New lists the genres, pages are fetched with the limit and cursor query parameters.
+func New(log *slog.Logger, lister GenreLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.genre.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		limit, after, err := cursor.ParsePage(r.URL.Query(), defaultLimit, maxLimit)
+		if err != nil {
+			log.Error("invalid page", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+
+		genres, next, err := lister.ListGenres(r.Context(), limit, after)
+		if err != nil {
+			log.Error("failed to list genres", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to list genres"))
+
+			return
+		}
+
+		items := make([]GenreResponse, len(genres))
+		for i, g := range genres {
+			items[i] = GenreResponse{
+				ID:       g.ID,
+				ParentID: g.ParentID,
+				Slug:     g.Slug,
+				Name:     g.Name,
+			}
+		}
+
+		resp := Response{Items: items}
+		if next != nil {
+			resp.NextCursor = cursor.Encode(*next)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, resp)
+	}
+}
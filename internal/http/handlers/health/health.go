@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/health"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/go-chi/render"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+type Response struct {
+	response.Response
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type Checker interface {
+	Run(ctx context.Context) ([]health.Result, bool)
+}
+
+// Live reports that the process is up and serving requests. It never checks dependencies,
+// so a broken database doesn't get the process restarted.
+func Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: statusOK,
+		})
+	}
+}
+
+// Ready runs the registered checks and answers 503 when any of them fails.
+func Ready(log *slog.Logger, checker Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.Ready"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		results, ok := checker.Run(r.Context())
+
+		resp := Response{
+			Status: statusOK,
+			Checks: make(map[string]string, len(results)),
+		}
+		for _, res := range results {
+			if res.Error != nil {
+				log.Warn("readiness check failed", slog.String("check", res.Name), slog.String("error", res.Error.Error()))
+
+				resp.Checks[res.Name] = res.Error.Error()
+				continue
+			}
+			resp.Checks[res.Name] = statusOK
+		}
+
+		if !ok {
+			resp.Status = statusFail
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			render.JSON(w, r, resp)
+
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, resp)
+	}
+}
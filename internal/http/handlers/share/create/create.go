@@ -0,0 +1,128 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/shares"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+const sharePath = "/share/"
+
+type Request struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxPlays  *int       `json:"max_plays,omitempty" validate:"omitempty,min=1"`
+}
+
+type Response struct {
+	response.Response
+	ID        int64      `json:"id"`
+	Token     string     `json:"token"`
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxPlays  *int       `json:"max_plays,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ShareCreator interface {
+	CreateShare(ctx context.Context, user models.User, trackID int64, expiresAt *time.Time, maxPlays *int) (models.ShareLink, string, error)
+}
+
+func New(log *slog.Logger, creator ShareCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.share.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		trackID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || trackID <= 0 {
+			log.Error("invalid track id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		var req Request
+
+		if r.ContentLength != 0 {
+			if err := render.DecodeJSON(r.Body, &req); err != nil {
+				log.Error("failed to decode request body", logger.Err(err))
+
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, response.Error("failed to decode request"))
+
+				return
+			}
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		link, raw, err := creator.CreateShare(r.Context(), user, trackID, req.ExpiresAt, req.MaxPlays)
+		switch {
+		case errors.Is(err, shares.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, shares.ErrInvalidLimits):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid share limits"))
+
+			return
+		case errors.Is(err, shares.ErrPrivateTrack):
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, response.Error("private tracks can't be shared"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to create share link", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to create share link"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, Response{
+			ID:        link.ID,
+			Token:     raw,
+			URL:       sharePath + raw,
+			ExpiresAt: link.ExpiresAt,
+			MaxPlays:  link.MaxPlays,
+			CreatedAt: link.CreatedAt,
+		})
+	}
+}
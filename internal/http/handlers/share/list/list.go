@@ -0,0 +1,120 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/cursor"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/shares"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Response is a page of links, newest first. NextCursor fetches the next page and is empty on the last one.
+type Response struct {
+	response.Response
+	Items      []ShareResponse `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type ShareResponse struct {
+	ID        int64      `json:"id"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxPlays  *int       `json:"max_plays,omitempty"`
+	Plays     int        `json:"plays"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ShareLister interface {
+	ListShares(ctx context.Context, user models.User, trackID int64, limit int, after *models.Cursor) ([]models.ShareLink, *models.Cursor, error)
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// New lists the share links of a track, pages are fetched with the limit and cursor query parameters.
+func New(log *slog.Logger, lister ShareLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.share.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		trackID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || trackID <= 0 {
+			log.Error("invalid track id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		limit, after, err := cursor.ParsePage(r.URL.Query(), defaultLimit, maxLimit)
+		if err != nil {
+			log.Error("invalid page", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+
+		links, next, err := lister.ListShares(r.Context(), user, trackID, limit, after)
+		switch {
+		case errors.Is(err, shares.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to list share links", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to list share links"))
+
+			return
+		}
+
+		items := make([]ShareResponse, len(links))
+		for i, l := range links {
+			items[i] = ShareResponse{
+				ID:        l.ID,
+				ExpiresAt: l.ExpiresAt,
+				MaxPlays:  l.MaxPlays,
+				Plays:     l.Plays,
+				RevokedAt: l.RevokedAt,
+				CreatedAt: l.CreatedAt,
+			}
+		}
+
+		resp := Response{Items: items}
+		if next != nil {
+			resp.NextCursor = cursor.Encode(*next)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,96 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/list"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/shares"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const streamBaseURL = "/stream/%d/master.m3u8"
+
+type Response struct {
+	response.Response
+	list.TrackListResponse
+}
+
+type ShareResolver interface {
+	ResolveShare(ctx context.Context, raw string) (models.Track, error)
+}
+
+// New returns the track behind a share link with a stream URL that carries the share token.
+func New(log *slog.Logger, resolver ShareResolver, signer list.StreamSigner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.share.resolve.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		raw := chigo.URLParam(r, "token")
+
+		track, err := resolver.ResolveShare(r.Context(), raw)
+		if errors.Is(err, shares.ErrInvalidShare) || errors.Is(err, shares.ErrTrackNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("share link not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to resolve share link", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to resolve share link"))
+
+			return
+		}
+
+		var viewerID int64
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			viewerID = user.ID
+		}
+
+		item, err := list.MapTrackToResponse(models.TrackListItem{
+			ID:         track.ID,
+			Title:      track.Title,
+			CreatedAt:  track.CreatedAt,
+			GainDB:     track.GainDB,
+			HasCover:   track.HasCover,
+			HasPreview: track.PreviewPrefix != nil,
+			HasDASH:    track.HasDASH,
+		}, streamBaseURL, viewerID, signer)
+		if err != nil {
+			log.Error("failed to sign stream url", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to resolve share link"))
+
+			return
+		}
+		item.StreamURL = media.AppendQuery(item.StreamURL, "share", raw)
+		if item.DashURL != "" {
+			item.DashURL = media.AppendQuery(item.DashURL, "share", raw)
+		}
+		if item.PreviewURL != "" {
+			item.PreviewURL = media.AppendQuery(item.PreviewURL, "share", raw)
+		}
+		if item.CoverURL != "" {
+			item.CoverURL = media.AppendQuery(item.CoverURL, "share", raw)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, Response{
+			TrackListResponse: item,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package revoke
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/shares"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type ShareRevoker interface {
+	RevokeShare(ctx context.Context, user models.User, trackID int64, id int64) error
+}
+
+func New(log *slog.Logger, revoker ShareRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.share.revoke.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		trackID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || trackID <= 0 {
+			log.Error("invalid track id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		shareStr := chigo.URLParam(r, "shareID")
+		shareID, err := strconv.ParseInt(shareStr, 10, 64)
+		if err != nil || shareID <= 0 {
+			log.Error("invalid share id", slog.String("id", shareStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid share id"))
+
+			return
+		}
+
+		err = revoker.RevokeShare(r.Context(), user, trackID, shareID)
+		switch {
+		case errors.Is(err, shares.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, shares.ErrShareNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("share link not found"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to revoke share link", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to revoke share link"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
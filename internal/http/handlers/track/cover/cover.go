@@ -0,0 +1,110 @@
+package cover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	coversvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/cover"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Covers only change when a track is reprocessed, so clients revalidate them cheaply.
+const cacheControl = "public, max-age=86400"
+
+type CoverProvider interface {
+	GetCover(ctx context.Context, req coversvc.Request) (io.ReadCloser, storage.ObjectInfo, error)
+}
+
+// New creates the cover handler. The format query parameter picks jpg or webp,
+// without it WebP is served to clients that accept it.
+func New(log *slog.Logger, provider CoverProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.cover.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		trackID, err := strconv.ParseInt(chigo.URLParam(r, "id"), 10, 64)
+		if err != nil || trackID <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		size, err := strconv.Atoi(chigo.URLParam(r, "size"))
+		if err != nil || size <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid size"))
+
+			return
+		}
+
+		req := coversvc.Request{
+			TrackID:    trackID,
+			Size:       size,
+			Format:     r.URL.Query().Get("format"),
+			ShareToken: r.URL.Query().Get("share"),
+		}
+		if req.Format == "" {
+			w.Header().Set("Vary", "Accept")
+			if strings.Contains(r.Header.Get("Accept"), "image/webp") {
+				req.Format = media.CoverWebP
+			}
+		}
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			req.User = &user
+		}
+
+		rc, info, err := provider.GetCover(r.Context(), req)
+		switch {
+		case errors.Is(err, coversvc.ErrFormat):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unsupported format"))
+
+			return
+		case errors.Is(err, coversvc.ErrAccessDenied):
+			log.Warn("access denied", logger.Err(err))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("access denied"))
+
+			return
+		case errors.Is(err, coversvc.ErrTrackNotFound), errors.Is(err, coversvc.ErrCoverNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("cover not found"))
+
+			return
+		case err != nil:
+			log.Error("failed to get cover", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get cover"))
+
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("Cache-Control", cacheControl)
+		if info.ETag != "" {
+			w.Header().Set("ETag", `"`+info.ETag+`"`)
+		}
+
+		if _, err := io.Copy(w, rc); err != nil {
+			log.Info("cover transfer interrupted", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	downloadsvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/download"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// retryAfter is how many seconds a client waits before asking again for a transcode being prepared.
+const retryAfter = "5"
+
+// Response is only sent while the download is being prepared.
+type Response struct {
+	response.Response
+	Status string `json:"status"`
+}
+
+type Downloader interface {
+	GetDownload(ctx context.Context, req downloadsvc.Request) (downloadsvc.File, error)
+}
+
+// New creates the download handler, the format query parameter is original, mp3, flac or opus.
+// A transcode that isn't ready yet is answered with 202 and Retry-After. It must be mounted behind auth.Required.
+func New(log *slog.Logger, downloader Downloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.download.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		idStr := chigo.URLParam(r, "id")
+		trackID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || trackID <= 0 {
+			log.Error("invalid track id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		file, err := downloader.GetDownload(r.Context(), downloadsvc.Request{
+			TrackID: trackID,
+			Format:  r.URL.Query().Get("format"),
+			User:    user,
+		})
+		switch {
+		case errors.Is(err, downloadsvc.ErrFormat):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unsupported format"))
+
+			return
+		case errors.Is(err, downloadsvc.ErrAccessDenied):
+			log.Warn("access denied", logger.Err(err))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("access denied"))
+
+			return
+		case errors.Is(err, downloadsvc.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, downloadsvc.ErrTrackNotReady):
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, response.Error("track is not ready"))
+
+			return
+		case errors.Is(err, downloadsvc.ErrPending):
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusAccepted)
+			render.JSON(w, r, Response{Status: "pending"})
+
+			return
+		case err != nil:
+			log.Error("failed to get download", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get download"))
+
+			return
+		}
+		defer file.Body.Close()
+
+		w.Header().Set("Content-Type", file.Info.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+		if file.Info.Size > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(file.Info.Size, 10))
+		}
+
+		if _, err := io.Copy(w, file.Body); err != nil {
+			log.Info("download interrupted", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package key
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/tracks/keys"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type KeyProvider interface {
+	GetKey(ctx context.Context, req keys.Request) ([]byte, error)
+}
+
+func New(log *slog.Logger, keyProvider KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.key.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		idStr := chigo.URLParam(r, "id")
+		run := chigo.URLParam(r, "run")
+		indexStr := chigo.URLParam(r, "index")
+
+		trackID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || trackID <= 0 {
+			log.Error("invalid track id", slog.String("id", idStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		if !validRun(run) {
+			log.Error("invalid run", slog.String("run", run))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid run"))
+
+			return
+		}
+
+		index, err := strconv.Atoi(indexStr)
+		if err != nil || index < 0 {
+			log.Error("invalid key index", slog.String("index", indexStr))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid key index"))
+
+			return
+		}
+
+		req := keys.Request{
+			TrackID:    trackID,
+			Run:        run,
+			Index:      index,
+			Token:      r.URL.Query().Get("token"),
+			ShareToken: r.URL.Query().Get("share"),
+		}
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			req.User = &user
+		}
+
+		key, err := keyProvider.GetKey(r.Context(), req)
+		switch {
+		case errors.Is(err, keys.ErrAccessDenied):
+			log.Warn("access denied", logger.Err(err))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("access denied"))
+
+			return
+		case errors.Is(err, storage.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("not found"))
+
+			return
+		case err != nil:
+			log.Error("failed to get key", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get key"))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(key)
+	}
+}
+
+// validRun accepts the base36 run names of media.NewRun, the legacy route has no run at all.
+func validRun(run string) bool {
+	if len(run) > 32 {
+		return false
+	}
+	for _, c := range run {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
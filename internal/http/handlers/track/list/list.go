@@ -2,15 +2,24 @@ package list
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Sheridanlk/Music-Service/internal/authz"
 	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/cursor"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
 	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/lib/streamtoken"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tags"
 	"github.com/Sheridanlk/Music-Service/internal/logger"
 	"github.com/go-chi/render"
 )
@@ -19,31 +28,91 @@ const (
 	defaultOffset = 0
 	defaultLimit  = 20
 	maxLimit      = 200
-	streamBaseURL = "/stream/%d/index.m3u8"
+	streamBaseURL = "/stream/%d/master.m3u8"
+	previewURL    = "/preview/%d/index.m3u8"
+	losslessURL   = "/stream/%d/lossless/index.m3u8"
+	dashURL       = "/stream/%d/manifest.mpd"
+	coverURL      = "/tracks/%d/cover/%d"
+	coverSize     = 300
 )
 
+// Response is a page of tracks. NextCursor fetches the following page and is absent on the last one,
+// Facets are left out of the pages fetched with a cursor since they don't change from page to page.
 type Response struct {
 	response.Response
-	Items []TrackListResponse `json:"items,omitempty"`
+	Items      []TrackListResponse `json:"items,omitempty"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	Facets     *FacetsResponse     `json:"facets,omitempty"`
 }
 
+// FacetsResponse counts every track matching the filter, Total included, not only the listed page.
+// Tags only holds the most used tags.
+type FacetsResponse struct {
+	Total  int             `json:"total"`
+	Genres []FacetResponse `json:"genres"`
+	Tags   []FacetResponse `json:"tags"`
+}
+
+// FacetResponse is a genre or a tag with the number of matching tracks, Name is only set for genres.
+type FacetResponse struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// TrackListResponse is a playable track. GainDB is the ReplayGain-style adjustment that brings the track
+// to the target loudness, it is 0 when the stream is already normalized and absent when it wasn't measured.
+// CoverURL is absent for tracks without cover art, other sizes are served under the same path.
+// PreviewURL is the short clip anonymous listeners get, absent until it is cut.
+// StreamURL is the HLS playlist and DashURL the MPEG-DASH manifest of the same fragments, absent for tracks
+// segmented without one. LosslessURL is only playable for listeners whose plan includes lossless streaming.
+// ArtistID is the uploader and Genre the slug of the track genre.
 type TrackListResponse struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"created_at"`
-	StreamURL string    `json:"stream_url"`
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	CreatedAt   time.Time `json:"created_at"`
+	StreamURL   string    `json:"stream_url"`
+	DashURL     string    `json:"dash_url,omitempty"`
+	PreviewURL  string    `json:"preview_url,omitempty"`
+	LosslessURL string    `json:"lossless_url,omitempty"`
+	CoverURL    string    `json:"cover_url,omitempty"`
+	GainDB      *float64  `json:"gain_db,omitempty"`
+	ArtistID    *int64    `json:"artist_id,omitempty"`
+	Genre       string    `json:"genre,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Duration    *float64  `json:"duration_seconds,omitempty"`
+	Plays       int64     `json:"plays"`
 }
 
 type Lister interface {
-	GetTracksList(ctx context.Context, count int, offset int) ([]models.TrackListItem, error)
+	GetTracksList(ctx context.Context, filter models.TrackFilter) (models.TrackPage, error)
+}
+
+type StreamSigner interface {
+	Sign(trackID int64, userID string) (string, error)
 }
 
-func New(log *slog.Logger, lister Lister) http.HandlerFunc {
+// New creates the tracks list handler. Stream URLs are left unsigned when signer is nil.
+// Tracks are filtered by the genre, tag, artist, duration_min and duration_max query parameters
+// and ordered by sort, see parseFilter. Pages are fetched with the cursor of the previous response,
+// offset is still accepted but pages shift when tracks are uploaded.
+func New(log *slog.Logger, lister Lister, signer StreamSigner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.track.list.New"
 
 		log := log.With(slog.String("op", op))
 
+		var viewerID int64
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			viewerID = user.ID
+			if err := authz.CanReadTracks(user); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, response.Error("forbidden"))
+
+				return
+			}
+		}
+
 		limitRaw := strings.TrimSpace(r.URL.Query().Get("limit"))
 		offsetRaw := strings.TrimSpace(r.URL.Query().Get("offset"))
 
@@ -87,7 +156,33 @@ func New(log *slog.Logger, lister Lister) http.HandlerFunc {
 			}
 		}
 
-		list, err := lister.GetTracksList(r.Context(), limit, offset)
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			log.Error("invalid filter", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+		filter.ViewerID = viewerID
+		filter.Limit = limit
+		filter.Offset = offset
+
+		if token := strings.TrimSpace(r.URL.Query().Get("cursor")); token != "" {
+			after, err := cursor.DecodeTrack(token, filter.Sort)
+			if err != nil || offsetRaw != "" {
+				log.Error("invalid cursor", slog.String("cursor", token))
+
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, response.Error("invalid cursor: must come from a listing with the same sort and without offset"))
+
+				return
+			}
+			filter.After = &after
+		}
+
+		page, err := lister.GetTracksList(r.Context(), filter)
 		if err != nil {
 			log.Error("failed to get tracks list", logger.Err(err))
 
@@ -97,30 +192,182 @@ func New(log *slog.Logger, lister Lister) http.HandlerFunc {
 			return
 		}
 
-		respList := MapTracksToResponse(list, streamBaseURL)
+		respList, err := MapTracksToResponse(page.Items, streamBaseURL, viewerID, signer)
+		if err != nil {
+			log.Error("failed to sign stream urls", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get tracks list"))
+
+			return
+		}
 
 		w.WriteHeader(http.StatusOK)
-		render.JSON(w, r, Response{
+		resp := Response{
 			Items: respList,
-		})
+		}
+		if page.Next != nil {
+			resp.NextCursor = cursor.EncodeTrack(*page.Next)
+		}
+		if page.Facets != nil {
+			resp.Facets = mapFacets(*page.Facets)
+		}
+
+		render.JSON(w, r, resp)
 	}
 }
 
-func MapTrackToResponse(t models.TrackListItem, streamBaseURL string) TrackListResponse {
-	return TrackListResponse{
+// parseFilter reads the listing filter from the query. Genre is a genre slug and matches its subgenres,
+// tag may be repeated or comma separated and every tag must match, artist is the uploader id,
+// durations are in seconds and sort is newest, title or popularity. Errors are meant for the client.
+func parseFilter(q url.Values) (models.TrackFilter, error) {
+	filter := models.TrackFilter{
+		Genre: strings.TrimSpace(q.Get("genre")),
+		Sort:  strings.TrimSpace(q.Get("sort")),
+	}
+
+	var raw []string
+	for _, v := range q["tag"] {
+		raw = append(raw, tags.Split(v)...)
+	}
+	normalized, err := tags.Normalize(raw)
+	if err != nil {
+		return filter, errors.New("invalid tag")
+	}
+	if len(normalized) > 0 {
+		filter.Tags = normalized
+	}
+
+	if v := strings.TrimSpace(q.Get("artist")); v != "" {
+		filter.ArtistID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || filter.ArtistID <= 0 {
+			return filter, errors.New("invalid artist: must be a user id")
+		}
+	}
+
+	if filter.DurationMin, err = parseSeconds(q.Get("duration_min")); err != nil {
+		return filter, errors.New("invalid duration_min: must be seconds")
+	}
+	if filter.DurationMax, err = parseSeconds(q.Get("duration_max")); err != nil {
+		return filter, errors.New("invalid duration_max: must be seconds")
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.SortNewest
+	case models.SortNewest, models.SortTitle, models.SortPopularity:
+	default:
+		return filter, errors.New("invalid sort: must be newest, title or popularity")
+	}
+
+	return filter, nil
+}
+
+func parseSeconds(v string) (*time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return nil, errors.New("invalid seconds")
+	}
+
+	d := time.Duration(seconds * float64(time.Second))
+	return &d, nil
+}
+
+func mapFacets(facets models.TrackFacets) *FacetsResponse {
+	resp := &FacetsResponse{
+		Total:  facets.Total,
+		Genres: make([]FacetResponse, len(facets.Genres)),
+		Tags:   make([]FacetResponse, len(facets.Tags)),
+	}
+	for i, f := range facets.Genres {
+		resp.Genres[i] = FacetResponse{Value: f.Value, Name: f.Name, Count: f.Count}
+	}
+	for i, f := range facets.Tags {
+		resp.Tags[i] = FacetResponse{Value: f.Value, Count: f.Count}
+	}
+
+	return resp
+}
+
+// MapTrackToResponse builds the listing entry of the track with stream URLs signed for the viewer, 0 for anonymous listeners.
+func MapTrackToResponse(t models.TrackListItem, streamBaseURL string, viewerID int64, signer StreamSigner) (TrackListResponse, error) {
+	streamURL, err := StreamURL(streamBaseURL, t.ID, viewerID, signer)
+	if err != nil {
+		return TrackListResponse{}, err
+	}
+
+	resp := TrackListResponse{
 		ID:        t.ID,
 		Title:     t.Title,
 		CreatedAt: t.CreatedAt,
-		StreamURL: fmt.Sprintf(streamBaseURL, t.ID),
+		StreamURL: streamURL,
+		GainDB:    t.GainDB,
+		ArtistID:  t.ArtistID,
+		Tags:      t.Tags,
+		Plays:     t.Plays,
 	}
+	if t.Genre != nil {
+		resp.Genre = *t.Genre
+	}
+	if t.Duration != nil {
+		seconds := t.Duration.Seconds()
+		resp.Duration = &seconds
+	}
+	if t.HasCover {
+		resp.CoverURL = fmt.Sprintf(coverURL, t.ID, coverSize)
+	}
+	if t.HasPreview {
+		resp.PreviewURL, err = StreamURL(previewURL, t.ID, viewerID, signer)
+		if err != nil {
+			return TrackListResponse{}, err
+		}
+	}
+	if t.HasDASH {
+		resp.DashURL, err = StreamURL(dashURL, t.ID, viewerID, signer)
+		if err != nil {
+			return TrackListResponse{}, err
+		}
+	}
+	if t.HasLossless {
+		resp.LosslessURL, err = StreamURL(losslessURL, t.ID, viewerID, signer)
+		if err != nil {
+			return TrackListResponse{}, err
+		}
+	}
+
+	return resp, nil
 }
 
-func MapTracksToResponse(tracks []models.TrackListItem, streamBaseURL string) []TrackListResponse {
+func MapTracksToResponse(tracks []models.TrackListItem, streamBaseURL string, viewerID int64, signer StreamSigner) ([]TrackListResponse, error) {
 	result := make([]TrackListResponse, len(tracks))
 
 	for i, t := range tracks {
-		result[i] = MapTrackToResponse(t, streamBaseURL)
+		item, err := MapTrackToResponse(t, streamBaseURL, viewerID, signer)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = item
+	}
+
+	return result, nil
+}
+
+// StreamURL builds the playlist URL for the track and appends a token signed for the viewer when signer is set.
+func StreamURL(streamBaseURL string, trackID int64, viewerID int64, signer StreamSigner) (string, error) {
+	streamURL := fmt.Sprintf(streamBaseURL, trackID)
+	if signer == nil {
+		return streamURL, nil
+	}
+
+	token, err := signer.Sign(trackID, streamtoken.Subject(viewerID))
+	if err != nil {
+		return "", err
 	}
 
-	return result
+	return media.AppendQuery(streamURL, "token", token), nil
 }
@@ -0,0 +1,271 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	lyricslib "github.com/Sheridanlk/Music-Service/internal/lib/lyrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	lyricssvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/lyrics"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxBodySize leaves room for the JSON escaping of the largest accepted lyrics.
+const maxBodySize = 2 * lyricssvc.MaxSize
+
+// Request replaces the lyrics of a track. LRC lyrics are validated line by line.
+type Request struct {
+	Format   string `json:"format" validate:"required,oneof=plain lrc"`
+	Text     string `json:"text" validate:"required"`
+	Language string `json:"language" validate:"omitempty,bcp47_language_tag"`
+}
+
+// Response carries the lyrics. Synced lyrics have StartMS set on every line,
+// an empty line ends the previous one.
+type Response struct {
+	response.Response
+	Format    string    `json:"format,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	Synced    bool      `json:"synced"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Lines     []Line    `json:"lines,omitempty"`
+}
+
+type Line struct {
+	StartMS *int64 `json:"start_ms,omitempty"`
+	Text    string `json:"text"`
+}
+
+type LyricsProvider interface {
+	GetLyrics(ctx context.Context, req lyricssvc.Request) (models.Lyrics, []lyricslib.Line, error)
+}
+
+type LyricsSetter interface {
+	SetLyrics(ctx context.Context, user models.User, lyrics models.Lyrics) error
+}
+
+type LyricsDeleter interface {
+	DeleteLyrics(ctx context.Context, user models.User, trackID int64) error
+}
+
+// New returns the lyrics of a track as JSON lines.
+func New(log *slog.Logger, provider LyricsProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.lyrics.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		trackID, ok := parseTrackID(w, r)
+		if !ok {
+			return
+		}
+
+		req := lyricssvc.Request{
+			TrackID:    trackID,
+			ShareToken: r.URL.Query().Get("share"),
+		}
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			req.User = &user
+		}
+
+		lyrics, lines, err := provider.GetLyrics(r.Context(), req)
+		switch {
+		case errors.Is(err, lyricssvc.ErrAccessDenied):
+			log.Warn("access denied", logger.Err(err))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("access denied"))
+
+			return
+		case errors.Is(err, lyricssvc.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, lyricssvc.ErrLyricsNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("lyrics not found"))
+
+			return
+		case err != nil:
+			log.Error("failed to get lyrics", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get lyrics"))
+
+			return
+		}
+
+		synced := lyrics.Format == models.LyricsLRC
+		resp := Response{
+			Format:    lyrics.Format,
+			Language:  lyrics.Language,
+			Synced:    synced,
+			UpdatedAt: lyrics.UpdatedAt,
+			Lines:     make([]Line, len(lines)),
+		}
+		for i, line := range lines {
+			resp.Lines[i].Text = line.Text
+			if synced {
+				ms := line.Start.Milliseconds()
+				resp.Lines[i].StartMS = &ms
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, resp)
+	}
+}
+
+// Set replaces the lyrics of a track, only its owner may do it. It must be mounted behind auth.Required.
+func Set(log *slog.Logger, setter LyricsSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.lyrics.Set"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		trackID, ok := parseTrackID(w, r)
+		if !ok {
+			return
+		}
+
+		var req Request
+
+		if err := render.DecodeJSON(http.MaxBytesReader(w, r.Body, maxBodySize), &req); err != nil {
+			log.Error("failed to decode request body", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(validateErr))
+
+			return
+		}
+
+		// Parsed here as well so the client learns which line is broken.
+		if _, err := lyricssvc.Parse(req.Format, req.Text); err != nil {
+			log.Error("invalid lyrics", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+
+		err := setter.SetLyrics(r.Context(), user, models.Lyrics{
+			TrackID:  trackID,
+			Format:   req.Format,
+			Text:     req.Text,
+			Language: req.Language,
+		})
+		switch {
+		case errors.Is(err, lyricssvc.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, lyricssvc.ErrFormat), errors.Is(err, lyricssvc.ErrInvalidLyrics):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid lyrics"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to set lyrics", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to set lyrics"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response.Response{})
+	}
+}
+
+// Delete removes the lyrics of a track, only its owner may do it. It must be mounted behind auth.Required.
+func Delete(log *slog.Logger, deleter LyricsDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.lyrics.Delete"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		user, _ := authmw.UserFromContext(r.Context())
+
+		trackID, ok := parseTrackID(w, r)
+		if !ok {
+			return
+		}
+
+		err := deleter.DeleteLyrics(r.Context(), user, trackID)
+		switch {
+		case errors.Is(err, lyricssvc.ErrTrackNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+
+			return
+		case errors.Is(err, lyricssvc.ErrLyricsNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("lyrics not found"))
+
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		case err != nil:
+			log.Error("failed to delete lyrics", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to delete lyrics"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response.Response{})
+	}
+}
+
+func parseTrackID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	trackID, err := strconv.ParseInt(chigo.URLParam(r, "id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid track id"))
+
+		return 0, false
+	}
+	return trackID, true
+}
@@ -2,31 +2,52 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
 	"github.com/Sheridanlk/Music-Service/internal/lib/response"
 	"github.com/Sheridanlk/Music-Service/internal/logger"
+	streamsvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/stream"
 	"github.com/Sheridanlk/Music-Service/internal/storage"
 	chigo "github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
+const (
+	playlistCacheControl = "no-cache"
+	signedCacheControl   = "private, no-store"
+	segmentCacheControl  = "public, max-age=31536000, immutable"
+)
+
 type Streamer interface {
-	GetStreamObject(ctx context.Context, trackID int64, file string, br *storage.ByteRange) (io.ReadCloser, string, int64, error)
+	GetStreamObject(ctx context.Context, req streamsvc.Request) (io.ReadCloser, storage.ObjectInfo, error)
 }
 
+// New creates the stream handler, the service picks the full or the preview rendition for the listener.
 func New(log *slog.Logger, streamer Streamer) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "handlers.track.stream.New"
+	return serve(log.With(slog.String("op", "handlers.track.stream.New")), streamer, streamsvc.RenditionDefault)
+}
+
+// Preview creates the handler that always serves the preview rendition.
+func Preview(log *slog.Logger, streamer Streamer) http.HandlerFunc {
+	return serve(log.With(slog.String("op", "handlers.track.stream.Preview")), streamer, streamsvc.RenditionPreview)
+}
 
-		log := log.With(
-			slog.String("op", op),
-		)
+// Lossless creates the handler of the lossless rendition, which only entitled listeners get.
+func Lossless(log *slog.Logger, streamer Streamer) http.HandlerFunc {
+	return serve(log.With(slog.String("op", "handlers.track.stream.Lossless")), streamer, streamsvc.RenditionLossless)
+}
+
+func serve(log *slog.Logger, streamer Streamer, rendition string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 
 		idStr := chigo.URLParam(r, "id")
 		file := chigo.URLParam(r, "file")
@@ -48,7 +69,27 @@ func New(log *slog.Logger, streamer Streamer) http.HandlerFunc {
 			}
 		}
 
-		rc, ct, _, err := streamer.GetStreamObject(r.Context(), trackID, file, br)
+		req := streamsvc.Request{
+			TrackID:    trackID,
+			File:       file,
+			Token:      r.URL.Query().Get("token"),
+			ShareToken: r.URL.Query().Get("share"),
+			Range:      br,
+			Rendition:  rendition,
+		}
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			req.User = &user
+		}
+
+		rc, info, err := streamer.GetStreamObject(r.Context(), req)
+		if errors.Is(err, streamsvc.ErrAccessDenied) {
+			log.Warn("access denied", logger.Err(err))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("access denied"))
+
+			return
+		}
 		if err != nil {
 			log.Error("failed to get file", logger.Err(err))
 
@@ -59,13 +100,18 @@ func New(log *slog.Logger, streamer Streamer) http.HandlerFunc {
 		}
 		defer rc.Close()
 
-		w.Header().Set("Content-Type", ct)
+		setCacheHeaders(w, file, info)
 
-		if strings.EqualFold(filepath.Ext(file), ".m3u8") {
-			w.Header().Set("Cache-Control", "no-store")
+		if notModified(r, info) {
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
 
-		if _, err := io.Copy(w, rc); err != nil {
+		w.Header().Set("Content-Type", info.ContentType)
+
+		n, err := io.Copy(w, rc)
+		metrics.StreamBytes.Add(float64(n))
+		if err != nil {
 			log.Info("stream interrupted", slog.String("error", err.Error()))
 
 			return
@@ -101,3 +147,77 @@ func parseRangeHeader(h string) (*storage.ByteRange, bool) {
 
 	return &storage.ByteRange{Start: start, End: end}, true
 }
+
+// setCacheHeaders sets validators from the object info and a Cache-Control policy based on the file type.
+// Segments never change once written, playlists, DASH manifests and lyrics cues must be revalidated on every request.
+// Manifests signed for the listener come without validators and are never stored, their URIs carry the tokens.
+func setCacheHeaders(w http.ResponseWriter, file string, info storage.ObjectInfo) {
+	validated := info.ETag != "" || !info.LastModified.IsZero()
+
+	if info.ETag != "" {
+		w.Header().Set("ETag", quoteETag(info.ETag))
+	}
+	if !info.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".m3u8", ".mpd":
+		if !validated {
+			w.Header().Set("Cache-Control", signedCacheControl)
+			return
+		}
+		w.Header().Set("Cache-Control", playlistCacheControl)
+	case ".vtt":
+		w.Header().Set("Cache-Control", playlistCacheControl)
+	case ".aac", ".ts", ".m4s", ".mp4":
+		w.Header().Set("Cache-Control", segmentCacheControl)
+	}
+}
+
+// notModified reports whether the client copy is still fresh.
+// If-None-Match takes precedence over If-Modified-Since as required by RFC 9110.
+func notModified(r *http.Request, info storage.ObjectInfo) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if info.ETag == "" {
+			return false
+		}
+		return etagMatches(inm, quoteETag(info.ETag))
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || info.LastModified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	return !info.LastModified.Truncate(time.Second).After(t)
+}
+
+// etagMatches performs the weak comparison of If-None-Match values against the current ETag.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
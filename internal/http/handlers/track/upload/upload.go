@@ -10,30 +10,55 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
 	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/lib/streamtoken"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tags"
 	"github.com/Sheridanlk/Music-Service/internal/logger"
+	"github.com/Sheridanlk/Music-Service/internal/services/quota"
+	uploadsvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/upload"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 )
 
-const maxUploadSize = int64(512 << 20) // 512 MB
+const (
+	maxUploadSize     = int64(512 << 20) // 512 MB
+	multipartOverhead = int64(1 << 20)   // room for the form fields and part headers
+)
 
+// Request holds the form fields of the upload. Genre is a genre slug and Tags a comma separated list.
 type Request struct {
-	Title string `json:"title" validate:"max=200"`
+	Title      string `json:"title" validate:"max=200"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	Genre      string `json:"genre" validate:"max=64"`
+	Tags       string `json:"tags" validate:"max=1000"`
 }
 
 type Response struct {
 	response.Response
-	ID     int64  `json:"id"`
-	Title  string `json:"title,omitempty"`
-	Stream string `json:"stream,omitempty"`
+	ID         int64  `json:"id"`
+	Title      string `json:"title,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+	Stream     string `json:"stream,omitempty"`
 }
 
 type TrackUploader interface {
-	UploadTrack(ctx context.Context, title string, filename string, reader io.Reader, size int64) (int64, error)
+	UploadTrack(ctx context.Context, user models.User, title, visibility string, filename string, reader io.Reader, size int64, cover *uploadsvc.Cover, taxonomy uploadsvc.Taxonomy) (int64, error)
+}
+
+type QuotaChecker interface {
+	CheckUpload(ctx context.Context, user models.User, size int64) (int64, error)
+}
+
+type StreamSigner interface {
+	Sign(trackID int64, userID string) (string, error)
 }
 
-func New(log *slog.Logger, uploader TrackUploader) http.HandlerFunc {
+// New creates the upload handler. Quotas are only checked by the uploader when quotaChecker is nil.
+func New(log *slog.Logger, uploader TrackUploader, quotaChecker QuotaChecker, signer StreamSigner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.track.upload.New"
 
@@ -41,11 +66,55 @@ func New(log *slog.Logger, uploader TrackUploader) http.HandlerFunc {
 			slog.String("op", op),
 		)
 
-		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		user, ok := authmw.UserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("authentication required"))
+
+			return
+		}
+
+		if err := authz.CanUpload(user); err != nil {
+			log.Warn("upload forbidden", slog.Int64("user_id", user.ID))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		}
+
+		limit := maxUploadSize
+		if quotaChecker != nil {
+			// The request size is checked before the body is read, the exact file size is checked by the uploader.
+			declared := int64(0)
+			if r.ContentLength > multipartOverhead {
+				declared = r.ContentLength - multipartOverhead
+			}
+
+			maxSize, err := quotaChecker.CheckUpload(r.Context(), user, declared)
+			if err != nil {
+				writeQuotaError(w, r, log, err)
+
+				return
+			}
+			if maxSize > 0 && maxSize+multipartOverhead < limit {
+				limit = maxSize + multipartOverhead
+			}
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
 
 		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
 			log.Error("failed to parse multipart form", logger.Err(err))
 
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, response.Error("file too large"))
+
+				return
+			}
+
 			w.WriteHeader(http.StatusBadRequest)
 			render.JSON(w, r, response.Error("failed to parse multipart form"))
 
@@ -54,7 +123,13 @@ func New(log *slog.Logger, uploader TrackUploader) http.HandlerFunc {
 		defer r.MultipartForm.RemoveAll()
 
 		req := Request{
-			Title: r.FormValue("title"),
+			Title:      r.FormValue("title"),
+			Visibility: strings.TrimSpace(r.FormValue("visibility")),
+			Genre:      strings.TrimSpace(r.FormValue("genre")),
+			Tags:       r.FormValue("tags"),
+		}
+		if req.Visibility == "" {
+			req.Visibility = models.VisibilityPublic
 		}
 
 		if err := validator.New().Struct(req); err != nil {
@@ -85,7 +160,63 @@ func New(log *slog.Logger, uploader TrackUploader) http.HandlerFunc {
 
 		size := hdr.Size
 
-		id, err := uploader.UploadTrack(r.Context(), req.Title, filename, file, size)
+		var cover *uploadsvc.Cover
+		coverFile, coverHdr, err := r.FormFile("cover")
+		switch {
+		case errors.Is(err, http.ErrMissingFile):
+		case err != nil:
+			log.Error("invalid cover", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid cover"))
+
+			return
+		default:
+			defer coverFile.Close()
+			cover = &uploadsvc.Cover{Reader: coverFile, Size: coverHdr.Size}
+		}
+
+		taxonomy := uploadsvc.Taxonomy{
+			Genre: req.Genre,
+			Tags:  tags.Split(req.Tags),
+		}
+
+		id, err := uploader.UploadTrack(r.Context(), user, req.Title, req.Visibility, filename, file, size, cover, taxonomy)
+		if errors.Is(err, uploadsvc.ErrInvalidTags) {
+			log.Warn("invalid tags", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error(fmt.Sprintf("invalid tags: up to %d tags of letters, digits and dashes, %d characters each", tags.MaxPerTrack, tags.MaxLength)))
+
+			return
+		}
+		if errors.Is(err, uploadsvc.ErrUnknownGenre) {
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unknown genre"))
+
+			return
+		}
+		if errors.Is(err, uploadsvc.ErrInvalidCover) {
+			log.Warn("invalid cover", logger.Err(err))
+
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid cover: must be a JPEG, PNG or WebP image up to 10 MB"))
+
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			log.Warn("upload forbidden", slog.Int64("user_id", user.ID))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("forbidden"))
+
+			return
+		}
+		if errors.Is(err, quota.ErrFileTooLarge) || errors.Is(err, quota.ErrQuotaExceeded) {
+			writeQuotaError(w, r, log, err)
+
+			return
+		}
 		if err != nil {
 			log.Error("faliled to upload track", logger.Err(err))
 
@@ -95,13 +226,42 @@ func New(log *slog.Logger, uploader TrackUploader) http.HandlerFunc {
 			return
 		}
 
-		stream := fmt.Sprintf("/stream/%d/index.m3u8", id)
+		stream := fmt.Sprintf("/stream/%d/master.m3u8", id)
+		if signer != nil {
+			token, err := signer.Sign(id, streamtoken.Subject(user.ID))
+			if err != nil {
+				log.Error("failed to sign stream url", logger.Err(err))
+			} else {
+				stream = media.AppendQuery(stream, "token", token)
+			}
+		}
 
 		w.WriteHeader(http.StatusOK)
 		render.JSON(w, r, Response{
-			ID:     id,
-			Title:  req.Title,
-			Stream: stream,
+			ID:         id,
+			Title:      req.Title,
+			Visibility: req.Visibility,
+			Stream:     stream,
 		})
 	}
 }
+
+func writeQuotaError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, quota.ErrFileTooLarge):
+		log.Warn("file too large", logger.Err(err))
+
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		render.JSON(w, r, response.Error("file too large"))
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		log.Warn("quota exceeded", logger.Err(err))
+
+		w.WriteHeader(http.StatusForbidden)
+		render.JSON(w, r, response.Error("quota exceeded"))
+	default:
+		log.Error("failed to check quota", logger.Err(err))
+
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to upload track"))
+	}
+}
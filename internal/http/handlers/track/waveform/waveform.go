@@ -0,0 +1,105 @@
+package waveform
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	authmw "github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/Sheridanlk/Music-Service/internal/logger"
+	waveformsvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/waveform"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	chigo "github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Waveforms only change when a track is reprocessed, so clients revalidate them cheaply.
+const cacheControl = "public, max-age=3600"
+
+type WaveformProvider interface {
+	GetWaveform(ctx context.Context, req waveformsvc.Request) (io.ReadCloser, storage.ObjectInfo, error)
+}
+
+func New(log *slog.Logger, provider WaveformProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.track.waveform.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		trackID, err := strconv.ParseInt(chigo.URLParam(r, "id"), 10, 64)
+		if err != nil || trackID <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid track id"))
+
+			return
+		}
+
+		req := waveformsvc.Request{
+			TrackID:    trackID,
+			Format:     r.URL.Query().Get("format"),
+			ShareToken: r.URL.Query().Get("share"),
+		}
+		if res := r.URL.Query().Get("resolution"); res != "" {
+			req.Resolution, err = strconv.Atoi(res)
+			if err != nil || req.Resolution <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, response.Error("invalid resolution"))
+
+				return
+			}
+		}
+		if user, ok := authmw.UserFromContext(r.Context()); ok {
+			req.User = &user
+		}
+
+		rc, info, err := provider.GetWaveform(r.Context(), req)
+		switch {
+		case errors.Is(err, waveformsvc.ErrResolution):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unsupported resolution"))
+
+			return
+		case errors.Is(err, waveformsvc.ErrFormat):
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, response.Error("unsupported format"))
+
+			return
+		case errors.Is(err, waveformsvc.ErrAccessDenied):
+			log.Warn("access denied", logger.Err(err))
+
+			w.WriteHeader(http.StatusForbidden)
+			render.JSON(w, r, response.Error("access denied"))
+
+			return
+		case errors.Is(err, waveformsvc.ErrTrackNotFound), errors.Is(err, waveformsvc.ErrWaveformNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, response.Error("waveform not found"))
+
+			return
+		case err != nil:
+			log.Error("failed to get waveform", logger.Err(err))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to get waveform"))
+
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("Cache-Control", cacheControl)
+		if info.ETag != "" {
+			w.Header().Set("ETag", `"`+info.ETag+`"`)
+		}
+
+		if _, err := io.Copy(w, rc); err != nil {
+			log.Info("waveform transfer interrupted", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/apikey"
+	"github.com/Sheridanlk/Music-Service/internal/lib/response"
+	"github.com/go-chi/render"
+)
+
+// Token cookies let browser clients such as the player authenticate without setting headers.
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+)
+
+type ctxKey struct{}
+
+type TokenParser interface {
+	ParseAccessToken(token string) (models.User, error)
+}
+
+type KeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (models.User, error)
+}
+
+// New authenticates the request by a Bearer access token or API key, or by the access token cookie,
+// and puts the user into the context.
+// Anonymous requests pass through, requests with an invalid token are rejected.
+func New(log *slog.Logger, parser TokenParser, keyAuthenticator KeyAuthenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		log.Info("auth middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFromRequest(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var (
+				user models.User
+				err  error
+			)
+			if apikey.IsAPIKey(token) {
+				user, err = keyAuthenticator.AuthenticateAPIKey(r.Context(), token)
+			} else {
+				user, err = parser.ParseAccessToken(token)
+			}
+			if err != nil {
+				log.Info("invalid access token", slog.String("error", err.Error()))
+
+				w.WriteHeader(http.StatusUnauthorized)
+				render.JSON(w, r, response.Error("invalid access token"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Required rejects requests that were not authenticated by New.
+func Required(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFromContext(r.Context()); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("authentication required"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Authorize rejects authenticated requests whose user doesn't satisfy the authz policy.
+// It must be used after Required.
+func Authorize(policy func(user models.User) error) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user, _ := UserFromContext(r.Context())
+
+			if err := policy(user); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, response.Error("forbidden"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func WithUser(ctx context.Context, user models.User) context.Context {
+	return context.WithValue(ctx, ctxKey{}, user)
+}
+
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(ctxKey{}).(models.User)
+	return user, ok
+}
+
+func tokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+
+	if c, err := r.Cookie(AccessTokenCookie); err == nil {
+		return c.Value
+	}
+
+	return ""
+}
+
+// SetTokenCookies stores the token pair in HttpOnly cookies. The refresh token is only sent to /auth endpoints.
+func SetTokenCookies(w http.ResponseWriter, pair models.TokenPair, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AccessTokenCookie,
+		Value:    pair.AccessToken,
+		Path:     "/",
+		Expires:  pair.ExpiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookie,
+		Value:    pair.RefreshToken,
+		Path:     "/auth",
+		Expires:  pair.RefreshExpiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func ClearTokenCookies(w http.ResponseWriter, secure bool) {
+	http.SetCookie(w, &http.Cookie{Name: AccessTokenCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: secure})
+	http.SetCookie(w, &http.Cookie{Name: RefreshTokenCookie, Path: "/auth", MaxAge: -1, HttpOnly: true, Secure: secure})
+}
@@ -3,11 +3,18 @@ package logger
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	chigo "github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// unmatchedRoute labels requests that didn't match any route, so random paths can't blow up metric cardinality.
+const unmatchedRoute = "unmatched"
+
 func New(log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log := log.With(
@@ -23,16 +30,28 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("trace_id", tracing.TraceID(r.Context())),
 			)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			t1 := time.Now()
 			defer func() {
+				duration := time.Since(t1)
+
 				entry.Info("request completed",
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
-					slog.String("duration", time.Since(t1).String()),
+					slog.String("duration", duration.String()),
 				)
+
+				route := unmatchedRoute
+				if rctx := chigo.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+				status := strconv.Itoa(ww.Status())
+
+				metrics.HTTPRequests.WithLabelValues(r.Method, route, status).Inc()
+				metrics.HTTPDuration.WithLabelValues(r.Method, route, status).Observe(duration.Seconds())
 			}()
 
 			next.ServeHTTP(ww, r)
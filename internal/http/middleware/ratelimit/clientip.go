@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPResolver finds the client address of a request. X-Forwarded-For is only honoured when the
+// request came from a trusted proxy, and hops added by trusted proxies are skipped from the right.
+type IPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewIPResolver parses trusted proxies given as CIDRs or single addresses.
+func NewIPResolver(trustedProxies []string) (*IPResolver, error) {
+	r := &IPResolver{}
+
+	for _, raw := range trustedProxies {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		if strings.Contains(raw, "/") {
+			prefix, err := netip.ParsePrefix(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+			}
+			r.trusted = append(r.trusted, prefix.Masked())
+
+			continue
+		}
+
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		r.trusted = append(r.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return r, nil
+}
+
+func (r *IPResolver) ClientIP(req *http.Request) string {
+	remote := parseAddr(req.RemoteAddr)
+	if !remote.IsValid() {
+		return req.RemoteAddr
+	}
+
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+
+	hops := forwardedHops(req)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr := parseAddr(hops[i])
+		if !addr.IsValid() {
+			break
+		}
+		if !r.isTrusted(addr) {
+			return addr.String()
+		}
+		remote = addr
+	}
+
+	return remote.String()
+}
+
+func (r *IPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func forwardedHops(req *http.Request) []string {
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+func parseAddr(raw string) netip.Addr {
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewIPResolver([]string{"10.0.0.0/8", " 2001:db8::1 ", ""})
+	if err != nil {
+		t.Fatalf("NewIPResolver: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{name: "no proxy", remote: "203.0.113.5:1234", want: "203.0.113.5"},
+		{name: "spoofed header from untrusted peer", remote: "203.0.113.5:1234", xff: []string{"198.51.100.7"}, want: "203.0.113.5"},
+		{name: "trusted proxy", remote: "10.0.0.1:1234", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted proxy without header", remote: "10.0.0.1:1234", want: "10.0.0.1"},
+		{name: "multi-hop chain", remote: "10.0.0.1:1234", xff: []string{"192.0.2.1, 198.51.100.7, 10.0.0.2"}, want: "198.51.100.7"},
+		{name: "chain split over headers", remote: "10.0.0.1:1234", xff: []string{"192.0.2.1, 198.51.100.7", "10.0.0.2"}, want: "198.51.100.7"},
+		{name: "only trusted hops", remote: "10.0.0.1:1234", xff: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "invalid hop stops the walk", remote: "10.0.0.1:1234", xff: []string{"198.51.100.7, 10.0.0.2, garbage"}, want: "10.0.0.1"},
+		{name: "ipv6 trusted proxy", remote: "[2001:db8::1]:443", xff: []string{"2001:db8:ffff::5"}, want: "2001:db8:ffff::5"},
+		{name: "ipv6 spoofed header from untrusted peer", remote: "[2001:db8::2]:443", xff: []string{"198.51.100.7"}, want: "2001:db8::2"},
+		{name: "ipv6 client behind ipv4 proxy", remote: "10.0.0.1:1234", xff: []string{"2001:db8:ffff::5"}, want: "2001:db8:ffff::5"},
+		{name: "ipv4-mapped peer", remote: "[::ffff:10.0.0.1]:80", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "peer without port", remote: "10.0.0.1", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "unparsable peer", remote: "@pipe", xff: []string{"198.51.100.7"}, want: "@pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := resolver.ClientIP(req); got != tt.want {
+				t.Fatalf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIPResolverInvalid(t *testing.T) {
+	for _, raw := range []string{"10.0.0.0/33", "proxy.local", "10.0.0.256"} {
+		if _, err := NewIPResolver([]string{raw}); err == nil {
+			t.Errorf("NewIPResolver(%q) accepted an invalid proxy", raw)
+		}
+	}
+}
@@ -4,28 +4,207 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	admingenre "github.com/Sheridanlk/Music-Service/internal/http/handlers/admin/genre"
+	adminquota "github.com/Sheridanlk/Music-Service/internal/http/handlers/admin/quota"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/admin/reprocess"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/admin/role"
+	apikeycreate "github.com/Sheridanlk/Music-Service/internal/http/handlers/apikey/create"
+	apikeylist "github.com/Sheridanlk/Music-Service/internal/http/handlers/apikey/list"
+	apikeyrevoke "github.com/Sheridanlk/Music-Service/internal/http/handlers/apikey/revoke"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/auth/login"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/auth/logout"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/auth/me"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/auth/refresh"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/auth/register"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/auth/usage"
+	genrelist "github.com/Sheridanlk/Music-Service/internal/http/handlers/genre/list"
+	healthcheck "github.com/Sheridanlk/Music-Service/internal/http/handlers/health"
 	"github.com/Sheridanlk/Music-Service/internal/http/handlers/player"
+	sharecreate "github.com/Sheridanlk/Music-Service/internal/http/handlers/share/create"
+	sharelist "github.com/Sheridanlk/Music-Service/internal/http/handlers/share/list"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/share/resolve"
+	sharerevoke "github.com/Sheridanlk/Music-Service/internal/http/handlers/share/revoke"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/cover"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/download"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/edit"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/genre"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/hide"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/key"
 	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/list"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/lyrics"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/preview"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/remove"
 	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/stream"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/tags"
 	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/upload"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/visibility"
+	"github.com/Sheridanlk/Music-Service/internal/http/handlers/track/waveform"
+	"github.com/Sheridanlk/Music-Service/internal/http/middleware/auth"
 	"github.com/Sheridanlk/Music-Service/internal/http/middleware/logger"
+	"github.com/Sheridanlk/Music-Service/internal/http/middleware/ratelimit"
+	"github.com/Sheridanlk/Music-Service/internal/http/middleware/tracing"
 	chigo "github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func Setup(log *slog.Logger, trackUploader upload.TrackUploader, streamer stream.Streamer, lister list.Lister) http.Handler {
+type AuthService interface {
+	register.UserRegisterer
+	login.UserLoginer
+	refresh.TokenRefresher
+	logout.UserLogouter
+	auth.TokenParser
+	role.RoleSetter
+}
+
+type APIKeyService interface {
+	auth.KeyAuthenticator
+	apikeycreate.KeyCreator
+	apikeylist.KeyLister
+	apikeyrevoke.KeyRevoker
+}
+
+type TrackManager interface {
+	edit.TrackEditor
+	remove.TrackDeleter
+	hide.TrackHider
+	visibility.VisibilitySetter
+	preview.PreviewStartSetter
+	genre.GenreSetter
+	tags.TagsSetter
+	reprocess.TrackReprocessor
+}
+
+type GenreService interface {
+	genrelist.GenreLister
+	admingenre.GenreCreator
+	admingenre.GenreUpdater
+	admingenre.GenreDeleter
+}
+
+type QuotaService interface {
+	upload.QuotaChecker
+	usage.UsageProvider
+	adminquota.QuotaSetter
+}
+
+type LyricsService interface {
+	lyrics.LyricsProvider
+	lyrics.LyricsSetter
+	lyrics.LyricsDeleter
+}
+
+type ShareService interface {
+	sharecreate.ShareCreator
+	sharelist.ShareLister
+	sharerevoke.ShareRevoker
+	resolve.ShareResolver
+}
+
+func Setup(
+	log *slog.Logger,
+	authService AuthService,
+	secureCookies bool,
+	apiKeyService APIKeyService,
+	trackUploader upload.TrackUploader,
+	streamer stream.Streamer,
+	lister list.Lister,
+	trackManager TrackManager,
+	shareService ShareService,
+	quotaService QuotaService,
+	waveformProvider waveform.WaveformProvider,
+	coverProvider cover.CoverProvider,
+	downloader download.Downloader,
+	lyricsService LyricsService,
+	genreService GenreService,
+	keyProvider key.KeyProvider,
+	signer list.StreamSigner,
+	limiter *ratelimit.Limiter,
+	metricsHandler http.Handler,
+	checker healthcheck.Checker,
+) http.Handler {
 	router := chigo.NewRouter()
 
 	router.Use(middleware.RequestID)
+	router.Use(tracing.New(log))
 	router.Use(logger.New(log))
 	router.Use(middleware.Recoverer)
 
+	router.Get("/healthz", healthcheck.Live())
+	router.Get("/readyz", healthcheck.Ready(log, checker))
+
 	router.Get("/player", player.New())
 
-	router.Post("/tracks", upload.New(log, trackUploader))
-	router.Get("/tracks", list.New(log, lister))
+	if metricsHandler != nil {
+		router.Handle("/metrics", metricsHandler)
+	}
+
+	router.Route("/auth", func(r chigo.Router) {
+		r.Post("/register", register.New(log, authService))
+		r.Post("/login", login.New(log, authService, secureCookies))
+		r.Post("/refresh", refresh.New(log, authService, secureCookies))
+		r.Post("/logout", logout.New(log, authService, secureCookies))
+	})
+
+	router.Group(func(r chigo.Router) {
+		r.Use(auth.New(log, authService, apiKeyService))
+
+		r.With(limiter.Limit(ratelimit.GroupList)).Get("/tracks", list.New(log, lister, signer))
+		r.With(limiter.Limit(ratelimit.GroupList)).Get("/genres", genrelist.New(log, genreService))
+		r.With(limiter.Limit(ratelimit.GroupList)).Get("/share/{token}", resolve.New(log, shareService, signer))
+		r.With(limiter.Limit(ratelimit.GroupStream)).Get("/stream/{id}/{file}", stream.New(log, streamer))
+		r.With(limiter.Limit(ratelimit.GroupStream)).Get("/stream/{id}/lossless/{file}", stream.Lossless(log, streamer))
+		r.With(limiter.Limit(ratelimit.GroupStream)).Get("/preview/{id}/{file}", stream.Preview(log, streamer))
+		r.With(limiter.Limit(ratelimit.GroupList)).Get("/tracks/{id}/waveform", waveform.New(log, waveformProvider))
+		r.With(limiter.Limit(ratelimit.GroupList)).Get("/tracks/{id}/cover/{size}", cover.New(log, coverProvider))
+		r.With(limiter.Limit(ratelimit.GroupList)).Get("/tracks/{id}/lyrics", lyrics.New(log, lyricsService))
+		if keyProvider != nil {
+			// Playlists published before keys were kept per run still point to the route without one.
+			r.With(limiter.Limit(ratelimit.GroupStream)).Get("/keys/{id}/{index}", key.New(log, keyProvider))
+			r.With(limiter.Limit(ratelimit.GroupStream)).Get("/keys/{id}/{run}/{index}", key.New(log, keyProvider))
+		}
+
+		r.Group(func(r chigo.Router) {
+			r.Use(auth.Required)
+
+			r.Get("/me", me.New())
+			if quotaService != nil {
+				r.Get("/me/usage", usage.New(log, quotaService))
+			}
+			r.Get("/me/api-keys", apikeylist.New(log, apiKeyService))
+			r.Post("/me/api-keys", apikeycreate.New(log, apiKeyService))
+			r.Delete("/me/api-keys/{id}", apikeyrevoke.New(log, apiKeyService))
+			r.With(limiter.Limit(ratelimit.GroupUpload)).Post("/tracks", upload.New(log, trackUploader, quotaService, signer))
+			r.Patch("/tracks/{id}", edit.New(log, trackManager))
+			r.Delete("/tracks/{id}", remove.New(log, trackManager))
+			r.Put("/tracks/{id}/hidden", hide.New(log, trackManager))
+			r.Put("/tracks/{id}/visibility", visibility.New(log, trackManager))
+			r.Put("/tracks/{id}/preview", preview.New(log, trackManager))
+			r.Put("/tracks/{id}/genre", genre.New(log, trackManager))
+			r.Put("/tracks/{id}/tags", tags.New(log, trackManager))
+			r.Put("/tracks/{id}/lyrics", lyrics.Set(log, lyricsService))
+			r.Delete("/tracks/{id}/lyrics", lyrics.Delete(log, lyricsService))
+			if downloader != nil {
+				r.With(limiter.Limit(ratelimit.GroupStream)).Get("/tracks/{id}/download", download.New(log, downloader))
+			}
+			r.Get("/tracks/{id}/shares", sharelist.New(log, shareService))
+			r.Post("/tracks/{id}/shares", sharecreate.New(log, shareService))
+			r.Delete("/tracks/{id}/shares/{shareID}", sharerevoke.New(log, shareService))
+
+			r.Route("/admin", func(r chigo.Router) {
+				r.Use(auth.Authorize(authz.CanAdminister))
 
-	router.Get("/stream/{id}/{file}", stream.New(log, streamer))
+				r.Post("/tracks/{id}/reprocess", reprocess.New(log, trackManager))
+				r.Put("/users/{id}/role", role.New(log, authService))
+				r.Post("/genres", admingenre.Create(log, genreService))
+				r.Put("/genres/{id}", admingenre.Update(log, genreService))
+				r.Delete("/genres/{id}", admingenre.Delete(log, genreService))
+				if quotaService != nil {
+					r.Put("/users/{id}/quota", adminquota.New(log, quotaService))
+				}
+			})
+		})
+	})
 
 	return router
 }
@@ -0,0 +1,34 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// Prefix marks API keys, so they can be told apart from JWT access tokens in the Authorization header.
+const Prefix = "msk_"
+
+const displayLen = len(Prefix) + 8
+
+// Generate returns a new random key, its display prefix and its hash. Only the hash may be stored.
+func Generate() (key string, display string, hash []byte, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", nil, err
+	}
+
+	key = Prefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	return key, key[:displayLen], Hash(key), nil
+}
+
+func Hash(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
@@ -0,0 +1,119 @@
+// Package cursor encodes listing positions into opaque tokens for keyset pagination.
+package cursor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+)
+
+var (
+	ErrInvalid      = errors.New("invalid cursor")
+	ErrInvalidLimit = errors.New("invalid limit: must be integer")
+)
+
+// track is the token layout of a models.TrackCursor, short keys keep tokens small.
+type track struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c"`
+	ID        int64     `json:"i"`
+	Title     string    `json:"t,omitempty"`
+	Plays     int64     `json:"p,omitempty"`
+}
+
+// EncodeTrack turns a track position into a URL safe token. Clients must treat the token as opaque.
+func EncodeTrack(position models.TrackCursor) string {
+	data, _ := json.Marshal(track(position))
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTrack reads a token made by EncodeTrack for a listing in the sort order.
+func DecodeTrack(token, sort string) (models.TrackCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.TrackCursor{}, ErrInvalid
+	}
+
+	var position track
+	if err := json.Unmarshal(data, &position); err != nil || position.ID <= 0 || position.Sort != sort {
+		return models.TrackCursor{}, ErrInvalid
+	}
+
+	return models.TrackCursor(position), nil
+}
+
+// item is the token layout of a models.Cursor.
+type item struct {
+	CreatedAt *time.Time `json:"c,omitempty"`
+	Name      string     `json:"n,omitempty"`
+	ID        int64      `json:"i"`
+}
+
+// Encode turns a listing position into a URL safe token. Clients must treat the token as opaque.
+func Encode(position models.Cursor) string {
+	it := item{Name: position.Name, ID: position.ID}
+	if !position.CreatedAt.IsZero() {
+		it.CreatedAt = &position.CreatedAt
+	}
+
+	data, _ := json.Marshal(it)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode reads a token made by Encode. Tokens of the track listing are rejected.
+func Decode(token string) (models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.Cursor{}, ErrInvalid
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var it item
+	if err := dec.Decode(&it); err != nil || it.ID <= 0 {
+		return models.Cursor{}, ErrInvalid
+	}
+
+	position := models.Cursor{Name: it.Name, ID: it.ID}
+	if it.CreatedAt != nil {
+		position.CreatedAt = *it.CreatedAt
+	}
+
+	return position, nil
+}
+
+// ParsePage reads the limit and cursor query parameters of a listing. The limit is defaultLimit when absent
+// or not positive and capped at maxLimit, the cursor is nil on the first page.
+func ParsePage(query url.Values, defaultLimit, maxLimit int) (int, *models.Cursor, error) {
+	limit := defaultLimit
+
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, nil, ErrInvalidLimit
+		}
+		if n > 0 {
+			limit = min(n, maxLimit)
+		}
+	}
+
+	token := strings.TrimSpace(query.Get("cursor"))
+	if token == "" {
+		return limit, nil, nil
+	}
+
+	after, err := Decode(token)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return limit, &after, nil
+}
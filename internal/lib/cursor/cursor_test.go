@@ -0,0 +1,147 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+)
+
+func encodeRaw(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestDecodeTrack(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	byTitle := models.TrackCursor{Sort: models.SortTitle, CreatedAt: created, ID: 7, Title: "Intro"}
+	byPlays := models.TrackCursor{Sort: models.SortPopularity, CreatedAt: created, ID: 8, Plays: 42}
+
+	tests := []struct {
+		name    string
+		token   string
+		sort    string
+		want    models.TrackCursor
+		wantErr bool
+	}{
+		{name: "title order", token: EncodeTrack(byTitle), sort: models.SortTitle, want: byTitle},
+		{name: "popularity order", token: EncodeTrack(byPlays), sort: models.SortPopularity, want: byPlays},
+		{name: "wrong sort", token: EncodeTrack(byTitle), sort: models.SortNewest, wantErr: true},
+		{name: "missing sort", token: encodeRaw(`{"c":"2026-03-01T12:00:00Z","i":7}`), sort: models.SortNewest, wantErr: true},
+		{name: "garbage", token: "!!not-base64!!", sort: models.SortNewest, wantErr: true},
+		{name: "not json", token: encodeRaw("cursor"), sort: models.SortNewest, wantErr: true},
+		{name: "wrong types", token: encodeRaw(`{"s":"newest","i":"7"}`), sort: models.SortNewest, wantErr: true},
+		{name: "no id", token: encodeRaw(`{"s":"newest","c":"2026-03-01T12:00:00Z"}`), sort: models.SortNewest, wantErr: true},
+		{name: "negative id", token: encodeRaw(`{"s":"newest","i":-1}`), sort: models.SortNewest, wantErr: true},
+		{name: "empty", token: "", sort: models.SortNewest, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeTrack(tt.token, tt.sort)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("DecodeTrack() error = %v, want %v", err, ErrInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeTrack() error = %v", err)
+			}
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) {
+				t.Fatalf("DecodeTrack() CreatedAt = %v, want %v", got.CreatedAt, tt.want.CreatedAt)
+			}
+			got.CreatedAt = tt.want.CreatedAt
+			if got != tt.want {
+				t.Fatalf("DecodeTrack() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	byDate := models.Cursor{CreatedAt: created, ID: 3}
+	byName := models.Cursor{Name: "Jazz", ID: 4}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    models.Cursor
+		wantErr bool
+	}{
+		{name: "newest first", token: Encode(byDate), want: byDate},
+		{name: "by name", token: Encode(byName), want: byName},
+		{name: "track token", token: EncodeTrack(models.TrackCursor{Sort: models.SortNewest, CreatedAt: created, ID: 3}), wantErr: true},
+		{name: "garbage", token: "%%%", wantErr: true},
+		{name: "no id", token: encodeRaw(`{"n":"Jazz"}`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("Decode() error = %v, want %v", err, ErrInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) || got.Name != tt.want.Name || got.ID != tt.want.ID {
+				t.Fatalf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	token := Encode(models.Cursor{Name: "Jazz", ID: 4})
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantAfter bool
+		wantErr   error
+	}{
+		{name: "defaults", query: "", wantLimit: 20},
+		{name: "limit", query: "limit=5", wantLimit: 5},
+		{name: "limit capped", query: "limit=500", wantLimit: 100},
+		{name: "non-positive limit", query: "limit=0", wantLimit: 20},
+		{name: "cursor", query: "cursor=" + token, wantLimit: 20, wantAfter: true},
+		{name: "bad limit", query: "limit=ten", wantErr: ErrInvalidLimit},
+		{name: "bad cursor", query: "cursor=nope", wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery: %v", err)
+			}
+
+			limit, after, err := ParsePage(query, 20, 100)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParsePage() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePage() error = %v", err)
+			}
+			if limit != tt.wantLimit {
+				t.Fatalf("ParsePage() limit = %d, want %d", limit, tt.wantLimit)
+			}
+			if (after != nil) != tt.wantAfter {
+				t.Fatalf("ParsePage() after = %+v, want set: %v", after, tt.wantAfter)
+			}
+		})
+	}
+}
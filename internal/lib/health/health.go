@@ -0,0 +1,78 @@
+// Package health runs readiness checks registered by the dependencies of a binary.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency is usable. It must respect the context deadline.
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Name  string
+	Error error
+}
+
+type Registry struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+}
+
+// New creates a registry that gives every check at most timeout to finish.
+func New(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Register adds a check. Registering the same name again replaces the check.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.checks[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.checks[name] = check
+}
+
+// Run executes all checks concurrently and returns their results in registration order.
+func (r *Registry) Run(ctx context.Context) ([]Result, bool) {
+	r.mu.RLock()
+	names := append([]string(nil), r.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	results := make([]Result, len(names))
+
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = Result{Name: names[i], Error: checks[i](ctx)}
+		}(i)
+	}
+	wg.Wait()
+
+	ok := true
+	for _, res := range results {
+		if res.Error != nil {
+			ok = false
+		}
+	}
+
+	return results, ok
+}
@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	jwtgo "github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwtgo.RegisteredClaims
+}
+
+// NewToken issues an HS256 access token for the user.
+func NewToken(user models.User, secret string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := Claims{
+		Email: user.Email,
+		Role:  user.Role,
+		RegisteredClaims: jwtgo.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwtgo.NewNumericDate(now),
+			ExpiresAt: jwtgo.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwtgo.NewWithClaims(jwtgo.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// ParseToken validates the access token and returns the user it was issued for.
+func ParseToken(token string, secret string) (models.User, error) {
+	var claims Claims
+
+	_, err := jwtgo.ParseWithClaims(token, &claims, func(t *jwtgo.Token) (any, error) {
+		return []byte(secret), nil
+	}, jwtgo.WithValidMethods([]string{jwtgo.SigningMethodHS256.Alg()}), jwtgo.WithExpirationRequired())
+	if err != nil {
+		return models.User{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+
+	return models.User{
+		ID:    id,
+		Email: claims.Email,
+		Role:  claims.Role,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package keywrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var ErrBadKey = errors.New("bad key encryption key")
+
+// Wrapper encrypts content keys with a key encryption key, so they are never stored in plain form.
+type Wrapper struct {
+	aead cipher.AEAD
+}
+
+// New creates a wrapper from a hex-encoded 16, 24 or 32 byte key encryption key.
+func New(kekHex string) (*Wrapper, error) {
+	const op = "keywrap.New"
+
+	kek, err := hex.DecodeString(kekHex)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrBadKey, err)
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrBadKey, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Wrapper{aead: aead}, nil
+}
+
+func (w *Wrapper) Wrap(key []byte) ([]byte, error) {
+	const op = "keywrap.Wrap"
+
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return w.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (w *Wrapper) Unwrap(wrapped []byte) ([]byte, error) {
+	const op = "keywrap.Unwrap"
+
+	n := w.aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, fmt.Errorf("%s: wrapped key is too short", op)
+	}
+
+	key, err := w.aead.Open(nil, wrapped[:n], wrapped[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,184 @@
+// Package lyrics parses plain and LRC lyrics and renders synced lyrics as WebVTT.
+package lyrics
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoLines   = errors.New("lyrics have no lines")
+	ErrTimestamp = errors.New("invalid timestamp")
+)
+
+// Line is a line of lyrics. Start is zero for plain lyrics, an empty Text in synced lyrics ends the previous line.
+type Line struct {
+	Start time.Duration
+	Text  string
+}
+
+var (
+	timeTag = regexp.MustCompile(`^\[(\d{1,3}):(\d{2})(?:[.:](\d{1,3}))?\]`)
+	metaTag = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]$`)
+)
+
+// ParsePlain splits plain lyrics into lines, leading and trailing blank lines are dropped.
+func ParsePlain(text string) ([]Line, error) {
+	var lines []Line
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		lines = append(lines, Line{Text: strings.TrimSpace(sc.Text())})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	start := slices.IndexFunc(lines, func(l Line) bool { return l.Text != "" })
+	if start < 0 {
+		return nil, ErrNoLines
+	}
+	end := len(lines)
+	for lines[end-1].Text == "" {
+		end--
+	}
+
+	return lines[start:end], nil
+}
+
+// ParseLRC parses time-synced lyrics in the LRC format. A line may carry several timestamps, the offset tag
+// shifts all of them and other metadata tags are ignored. Timestamps must be valid and not before zero,
+// the lines are returned sorted by start.
+func ParseLRC(text string) ([]Line, error) {
+	var lines []Line
+	var offset time.Duration
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" {
+			continue
+		}
+
+		if m := metaTag.FindStringSubmatch(raw); m != nil {
+			if strings.EqualFold(m[1], "offset") {
+				ms, err := strconv.Atoi(strings.TrimSpace(m[2]))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid offset %q", n, m[2])
+				}
+				// A positive offset makes the lyrics appear sooner.
+				offset = -time.Duration(ms) * time.Millisecond
+			}
+			continue
+		}
+
+		var starts []time.Duration
+		rest := raw
+		for {
+			m := timeTag.FindStringSubmatch(rest)
+			if m == nil {
+				break
+			}
+
+			start, err := parseTimestamp(m[1], m[2], m[3])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w %s", n, err, m[0])
+			}
+			starts = append(starts, start)
+			rest = rest[len(m[0]):]
+		}
+
+		if len(starts) == 0 {
+			return nil, fmt.Errorf("line %d: %w: line has no timestamp", n, ErrTimestamp)
+		}
+
+		for _, start := range starts {
+			lines = append(lines, Line{Start: start, Text: strings.TrimSpace(rest)})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(lines, func(l Line) bool { return l.Text != "" }) {
+		return nil, ErrNoLines
+	}
+
+	for i := range lines {
+		lines[i].Start += offset
+		if lines[i].Start < 0 {
+			return nil, fmt.Errorf("%w: offset moves a line before the start", ErrTimestamp)
+		}
+	}
+
+	slices.SortStableFunc(lines, func(a, b Line) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+
+	return lines, nil
+}
+
+// parseTimestamp parses the minutes, seconds and fraction of a [mm:ss.xx] tag.
+// The fraction is hundredths with two digits and milliseconds with three.
+func parseTimestamp(minutes, seconds, fraction string) (time.Duration, error) {
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	if s >= 60 {
+		return 0, ErrTimestamp
+	}
+
+	d := time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if fraction != "" {
+		f, _ := strconv.Atoi(fraction)
+		for range 3 - len(fraction) {
+			f *= 10
+		}
+		d += time.Duration(f) * time.Millisecond
+	}
+
+	return d, nil
+}
+
+// WebVTT renders synced lines as WebVTT cues. Every cue lasts until the next line starts,
+// the last one until end. Lines starting at or after end are dropped.
+func WebVTT(lines []Line, end time.Duration) []byte {
+	var out bytes.Buffer
+	out.WriteString("WEBVTT\n")
+
+	for i, line := range lines {
+		if line.Text == "" || line.Start >= end {
+			continue
+		}
+
+		cueEnd := end
+		if i+1 < len(lines) && lines[i+1].Start < end {
+			cueEnd = lines[i+1].Start
+		}
+		if cueEnd <= line.Start {
+			continue
+		}
+
+		fmt.Fprintf(&out, "\n%s --> %s\n%s\n", vttTimestamp(line.Start), vttTimestamp(cueEnd), escapeVTT(line.Text))
+	}
+
+	return out.Bytes()
+}
+
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeVTT(text string) string {
+	return vttEscaper.Replace(text)
+}
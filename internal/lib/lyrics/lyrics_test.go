@@ -0,0 +1,108 @@
+package lyrics
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLRC(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []Line
+		wantErr error
+	}{
+		{
+			name: "hundredths and milliseconds",
+			text: "[00:01.50]first\n[00:02.250]second\n[01:03]third",
+			want: []Line{
+				{Start: 1500 * time.Millisecond, Text: "first"},
+				{Start: 2250 * time.Millisecond, Text: "second"},
+				{Start: time.Minute + 3*time.Second, Text: "third"},
+			},
+		},
+		{
+			name: "multiple timestamps on a line",
+			text: "[00:10.00][00:30.00]chorus\n[00:20.00]verse",
+			want: []Line{
+				{Start: 10 * time.Second, Text: "chorus"},
+				{Start: 20 * time.Second, Text: "verse"},
+				{Start: 30 * time.Second, Text: "chorus"},
+			},
+		},
+		{
+			name: "metadata ignored and empty line kept",
+			text: "[ar:Artist]\n[ti:Title]\n\n[00:01.00]line\n[00:04.00]",
+			want: []Line{
+				{Start: time.Second, Text: "line"},
+				{Start: 4 * time.Second, Text: ""},
+			},
+		},
+		{
+			name: "positive offset shows lines sooner",
+			text: "[offset:500]\n[00:01.00]line",
+			want: []Line{{Start: 500 * time.Millisecond, Text: "line"}},
+		},
+		{
+			name: "negative offset shows lines later",
+			text: "[offset:-500]\n[00:01.00]line",
+			want: []Line{{Start: 1500 * time.Millisecond, Text: "line"}},
+		},
+		{
+			name:    "offset before the start",
+			text:    "[offset:1500]\n[00:01.00]line",
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "seconds of 60",
+			text:    "[00:60.00]line",
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "seconds over 60",
+			text:    "[01:75.00]line",
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "invalid second timestamp",
+			text:    "[00:10.00][00:99.00]line",
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "line without timestamp",
+			text:    "[00:01.00]line\nplain",
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "only empty lines",
+			text:    "[ar:Artist]\n[00:01.00]\n[00:02.00]",
+			wantErr: ErrNoLines,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLRC(tt.text)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseLRC() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLRC() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("ParseLRC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLRCInvalidOffset(t *testing.T) {
+	if _, err := ParseLRC("[offset:soon]\n[00:01.00]line"); err == nil {
+		t.Fatal("ParseLRC() accepted a non-numeric offset")
+	}
+}
@@ -12,6 +12,13 @@ func DetectContentType(name string) string {
 		return "application/octet-stream"
 	}
 
+	// Renditions only hold audio, so CMAF fragments and init segments are typed
+	// as audio whatever the system MIME table says.
+	switch ext {
+	case ".m4s", ".mp4":
+		return "audio/mp4"
+	}
+
 	if ct := mime.TypeByExtension(ext); ct != "" {
 		return ct
 	}
@@ -19,10 +26,20 @@ func DetectContentType(name string) string {
 	switch ext {
 	case ".m3u8":
 		return "application/vnd.apple.mpegurl"
+	case ".mpd":
+		return "application/dash+xml"
+	case ".vtt":
+		return "text/vtt"
 	case ".ts":
 		return "video/MP2T"
 	case ".aac":
 		return "audio/aac"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".flac":
+		return "audio/flac"
+	case ".opus":
+		return "audio/ogg"
 	default:
 		return "application/octet-stream"
 	}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Cover variants are stored as JPEG and lossless WebP.
+const (
+	CoverJPEG = "jpg"
+	CoverWebP = "webp"
+)
+
+// MaxCoverPixels bounds the decoded size of a cover, so a small file can't expand into a huge bitmap.
+const MaxCoverPixels = 64 << 20
+
+var (
+	ErrNoCover          = errors.New("no embedded cover")
+	ErrUnsupportedImage = errors.New("unsupported image")
+)
+
+// CoverFormat checks the header of a JPEG, PNG or WebP image and returns its format name.
+func CoverFormat(r io.Reader) (string, error) {
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxCoverPixels {
+		return "", fmt.Errorf("%w: %dx%d", ErrUnsupportedImage, cfg.Width, cfg.Height)
+	}
+	return format, nil
+}
+
+// DecodeCover decodes a JPEG, PNG or WebP image.
+func DecodeCover(data []byte) (image.Image, error) {
+	if _, err := CoverFormat(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+	return img, nil
+}
+
+// ResizeCover crops the centre square of img and scales it to size x size.
+// Images smaller than size are scaled up so every variant has the advertised dimensions.
+func ResizeCover(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// EncodeCover encodes img as a JPEG at quality or as a lossless WebP.
+func EncodeCover(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case CoverJPEG:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+	case CoverWebP:
+		if err := nativewebp.Encode(&buf, img, nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ExtractCover returns the attached picture of an audio file as stored in its tags.
+// Files without a picture return ErrNoCover.
+func ExtractCover(ctx context.Context, inputPath string) ([]byte, error) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	probe := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v",
+		"-show_entries", "stream=index",
+		"-of", "csv=p=0",
+		inputPath,
+	)
+	var probeOut, probeErr bytes.Buffer
+	probe.Stdout = &probeOut
+	probe.Stderr = &probeErr
+	if err := probe.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe command failed: %w: %s", err, probeErr.String())
+	}
+	if strings.TrimSpace(probeOut.String()) == "" {
+		return nil, ErrNoCover
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "ffmpeg", trace.WithAttributes(
+		attribute.String("ffmpeg.format", "cover"),
+	))
+	defer span.End()
+
+	// The picture is copied as is, the pipeline decodes whatever the tagger stored.
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-i", inputPath,
+		"-map", "0:v:0",
+		"-frames:v", "1",
+		"-c:v", "copy",
+		"-f", "image2pipe",
+		"-",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	metrics.FFmpegDuration.Observe(time.Since(start).Seconds())
+	tracing.RecordError(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg command failed: %w: %s", err, stderr.String())
+	}
+	if stdout.Len() == 0 {
+		return nil, ErrNoCover
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,188 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DASH codecs parameters of the rendition codecs.
+var dashCodecs = map[string]string{
+	CodecAAC:  "mp4a.40.2",
+	CodecFLAC: "fLaC",
+	CodecALAC: "alac",
+}
+
+var mapURIAttr = regexp.MustCompile(`#EXT-X-MAP:.*URI="([^"]*)"`)
+
+type mpd struct {
+	XMLName       xml.Name  `xml:"MPD"`
+	Xmlns         string    `xml:"xmlns,attr"`
+	Profiles      string    `xml:"profiles,attr"`
+	Type          string    `xml:"type,attr"`
+	Duration      string    `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime string    `xml:"minBufferTime,attr"`
+	Period        mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	AdaptationSet mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ContentType      string            `xml:"contentType,attr"`
+	MimeType         string            `xml:"mimeType,attr"`
+	SegmentAlignment bool              `xml:"segmentAlignment,attr"`
+	Representation   mpdRepresentation `xml:"Representation"`
+}
+
+type mpdRepresentation struct {
+	ID          string         `xml:"id,attr"`
+	Codecs      string         `xml:"codecs,attr"`
+	Bandwidth   int64          `xml:"bandwidth,attr"`
+	SegmentList mpdSegmentList `xml:"SegmentList"`
+}
+
+type mpdSegmentList struct {
+	Timescale      int             `xml:"timescale,attr"`
+	Initialization mpdURL          `xml:"Initialization"`
+	Timeline       []mpdTimelineS  `xml:"SegmentTimeline>S"`
+	SegmentURLs    []mpdSegmentURL `xml:"SegmentURL"`
+}
+
+type mpdURL struct {
+	SourceURL string `xml:"sourceURL,attr"`
+}
+
+type mpdTimelineS struct {
+	D int64 `xml:"d,attr"`
+}
+
+type mpdSegmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// mpdTimescale is the number of timeline units per second, segment durations are kept in milliseconds.
+const mpdTimescale = 1000
+
+// WriteMPD writes manifest.mpd next to the fMP4 HLS rendition in dir. The manifest lists the same init
+// segment and fragments as index.m3u8, so both formats are served from one copy of the media.
+// The bandwidth is the peak bitrate of a single fragment.
+func WriteMPD(dir string, codec string) error {
+	codecs, ok := dashCodecs[codec]
+	if !ok {
+		return fmt.Errorf("unsupported codec %q", codec)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "index.m3u8"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	list := mpdSegmentList{Timescale: mpdTimescale}
+
+	var total, bandwidth float64
+	var extinf float64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if m := mapURIAttr.FindStringSubmatch(line); m != nil {
+				list.Initialization.SourceURL = m[1]
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			extinf, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("bad segment duration %q: %w", value, err)
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			stat, err := os.Stat(filepath.Join(dir, line))
+			if err != nil {
+				return err
+			}
+			if extinf > 0 {
+				bandwidth = max(bandwidth, float64(stat.Size()*8)/extinf)
+			}
+
+			total += extinf
+			list.Timeline = append(list.Timeline, mpdTimelineS{D: int64(math.Round(extinf * mpdTimescale))})
+			list.SegmentURLs = append(list.SegmentURLs, mpdSegmentURL{Media: line})
+			extinf = 0
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	if list.Initialization.SourceURL == "" {
+		return errors.New("playlist has no init segment")
+	}
+	if len(list.SegmentURLs) == 0 {
+		return errors.New("playlist has no segments")
+	}
+
+	manifest := mpd{
+		Xmlns:         "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:      "urn:mpeg:dash:profile:isoff-main:2011",
+		Type:          "static",
+		Duration:      fmt.Sprintf("PT%.3fS", total),
+		MinBufferTime: fmt.Sprintf("PT%.3fS", float64(list.Timeline[0].D)/mpdTimescale),
+		Period: mpdPeriod{
+			AdaptationSet: mpdAdaptationSet{
+				ContentType:      "audio",
+				MimeType:         "audio/mp4",
+				SegmentAlignment: true,
+				Representation: mpdRepresentation{
+					ID:          codec,
+					Codecs:      codecs,
+					Bandwidth:   int64(math.Ceil(bandwidth)),
+					SegmentList: list,
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+
+	return os.WriteFile(filepath.Join(dir, "manifest.mpd"), out.Bytes(), 0644)
+}
+
+var mpdURLAttr = regexp.MustCompile(`(sourceURL|media)="([^"]*)"`)
+
+// RewriteMPD applies rewrite to the init segment and fragment URLs of a manifest written by WriteMPD.
+func RewriteMPD(r io.Reader, rewrite func(uri string) string) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return mpdURLAttr.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := mpdURLAttr.FindSubmatch(m)
+		uri := rewrite(html.UnescapeString(string(sub[2])))
+		return []byte(string(sub[1]) + `="` + html.EscapeString(uri) + `"`)
+	}), nil
+}
@@ -0,0 +1,116 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const keySize = 16
+
+type HLSKey struct {
+	Index int
+	Key   []byte
+}
+
+// EncryptHLS encrypts every segment of the playlist in outputDir with AES-128-CBC and inserts #EXT-X-KEY tags.
+// A new key is generated every segmentsPerKey segments, a single key is used when segmentsPerKey is not positive.
+// The IV of each segment is its media sequence number, so the playlist carries no IV attribute.
+func EncryptHLS(outputDir string, segmentsPerKey int, keyURI func(index int) string) ([]HLSKey, error) {
+	playlist := filepath.Join(outputDir, "index.m3u8")
+
+	raw, err := os.ReadFile(playlist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	var (
+		out      bytes.Buffer
+		keys     []HLSKey
+		mediaSeq int64
+		segment  int
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			mediaSeq, err = strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad media sequence: %w", err)
+			}
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			index := 0
+			if segmentsPerKey > 0 {
+				index = segment / segmentsPerKey
+			}
+
+			if len(keys) == index {
+				key := make([]byte, keySize)
+				if _, err := rand.Read(key); err != nil {
+					return nil, fmt.Errorf("failed to generate key: %w", err)
+				}
+				keys = append(keys, HLSKey{Index: index, Key: key})
+
+				fmt.Fprintf(&out, "#EXT-X-KEY:METHOD=AES-128,URI=\"%s\"\n", keyURI(index))
+			}
+
+		case line != "" && !strings.HasPrefix(line, "#"):
+			if len(keys) == 0 {
+				return nil, fmt.Errorf("segment %s has no #EXTINF", line)
+			}
+
+			iv := make([]byte, aes.BlockSize)
+			binary.BigEndian.PutUint64(iv[8:], uint64(mediaSeq)+uint64(segment))
+
+			if err := encryptFile(filepath.Join(outputDir, line), keys[len(keys)-1].Key, iv); err != nil {
+				return nil, fmt.Errorf("failed to encrypt segment %s: %w", line, err)
+			}
+
+			segment++
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist: %w", err)
+	}
+
+	if err := os.WriteFile(playlist, out.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write playlist: %w", err)
+	}
+
+	return keys, nil
+}
+
+func encryptFile(path string, key, iv []byte) error {
+	plain, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	pad := aes.BlockSize - len(plain)%aes.BlockSize
+	data := append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	return os.WriteFile(path, data, 0644)
+}
@@ -6,37 +6,213 @@ import (
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func ToHLS(ctx context.Context, inputPath string, outputDir string, segSeconds int) error {
+// CheckFFmpeg reports whether the binaries the worker runs are on PATH.
+func CheckFFmpeg(_ context.Context) error {
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return fmt.Errorf("%s not found in PATH: %w", bin, err)
+		}
+	}
+	return nil
+}
+
+// Codecs of the HLS renditions. AAC is lossy and always encoded at 128k stereo 44.1 kHz,
+// FLAC and ALAC keep the sample rate, channels and bit depth of the source.
+const (
+	CodecAAC  = "aac"
+	CodecFLAC = "flac"
+	CodecALAC = "alac"
+)
+
+// HLSOptions describes a rendition. With FMP4 the segments are CMAF fragments (.m4s) that
+// share an init.mp4, otherwise they are packed audio, which only AAC supports.
+// A non-empty AudioFilter is applied before encoding. DASH also writes an MPD manifest for the
+// fragments, it needs FMP4.
+type HLSOptions struct {
+	SegmentSeconds int
+	Codec          string
+	FMP4           bool
+	DASH           bool
+	AudioFilter    string
+}
+
+// ToHLS encodes the input to an HLS rendition in outputDir.
+func ToHLS(ctx context.Context, inputPath string, outputDir string, opts HLSOptions) error {
+	return encodeHLS(ctx, []string{"-i", inputPath}, outputDir, opts, "hls")
+}
+
+// ToHLSClip encodes length of the input from start to an HLS rendition, fading the clip in and out.
+func ToHLSClip(ctx context.Context, inputPath string, outputDir string, opts HLSOptions, start, length time.Duration) error {
+	fade := min(clipFade, length/4).Seconds()
+	filter := fmt.Sprintf("afade=t=in:d=%.3f,afade=t=out:st=%.3f:d=%.3f", fade, length.Seconds()-fade, fade)
+	if opts.AudioFilter != "" {
+		filter = opts.AudioFilter + "," + filter
+	}
+	opts.AudioFilter = filter
+
+	input := []string{
+		"-ss", fmt.Sprintf("%.3f", start.Seconds()),
+		"-t", fmt.Sprintf("%.3f", length.Seconds()),
+		"-i", inputPath,
+	}
+	return encodeHLS(ctx, input, outputDir, opts, "hls_clip")
+}
+
+// clipFade is the longest fade applied at both ends of a clip.
+const clipFade = 2 * time.Second
+
+func encodeHLS(ctx context.Context, input []string, outputDir string, opts HLSOptions, format string) error {
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
 		return fmt.Errorf("ffmpeg not found in PATH: %w", err)
 	}
 
-	playlist := filepath.Join(outputDir, "index.m3u8")
-	segPattern := filepath.Join(outputDir, "seg_%05d.aac")
+	codec := opts.Codec
+	if codec == "" {
+		codec = CodecAAC
+	}
+	if codec != CodecAAC && !opts.FMP4 {
+		return fmt.Errorf("%s segments need fmp4", codec)
+	}
+	if opts.DASH && !opts.FMP4 {
+		return fmt.Errorf("dash manifest needs fmp4")
+	}
 
-	args := []string{
-		"-y",
-		"-i", inputPath,
-		"-vn",
-		"-c:a", "aac",
-		"-b:a", "128k",
-		"-ar", "44100",
-		"-ac", "2",
+	args := append([]string{"-y"}, input...)
+	args = append(args, "-vn")
+	if opts.AudioFilter != "" {
+		args = append(args, "-af", opts.AudioFilter)
+	}
+
+	switch codec {
+	case CodecAAC:
+		args = append(args, "-c:a", "aac", "-b:a", "128k", "-ar", "44100", "-ac", "2")
+	case CodecFLAC, CodecALAC:
+		// FLAC in MP4 is still flagged experimental by older ffmpeg releases.
+		args = append(args, "-c:a", codec, "-strict", "experimental")
+	default:
+		return fmt.Errorf("unsupported codec %q", codec)
+	}
+
+	segPattern := filepath.Join(outputDir, "seg_%05d.aac")
+	args = append(args,
 		"-f", "hls",
-		"-hls_time", fmt.Sprintf("%d", segSeconds),
+		"-hls_time", fmt.Sprintf("%d", opts.SegmentSeconds),
 		"-hls_playlist_type", "vod",
-		"-hls_segment_filename", segPattern,
-		playlist,
+	)
+	if opts.FMP4 {
+		segPattern = filepath.Join(outputDir, "seg_%05d.m4s")
+		args = append(args, "-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init.mp4")
 	}
+	args = append(args,
+		"-hls_segment_filename", segPattern,
+		filepath.Join(outputDir, "index.m3u8"),
+	)
+
+	ctx, span := tracing.Tracer().Start(ctx, "ffmpeg", trace.WithAttributes(
+		attribute.String("ffmpeg.format", format),
+		attribute.String("ffmpeg.codec", codec),
+		attribute.Bool("ffmpeg.fmp4", opts.FMP4),
+		attribute.Bool("ffmpeg.dash", opts.DASH),
+		attribute.Int("ffmpeg.segment_seconds", opts.SegmentSeconds),
+	))
+	defer span.End()
 
 	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	start := time.Now()
+	err := cmd.Run()
+	metrics.FFmpegDuration.Observe(time.Since(start).Seconds())
+	tracing.RecordError(span, err)
+
+	if err != nil {
 		return fmt.Errorf("ffmpeg command failed: %w: %s", err, stderr.String())
 	}
+
+	if opts.DASH {
+		if err := WriteMPD(outputDir, codec); err != nil {
+			return fmt.Errorf("failed to write dash manifest: %w", err)
+		}
+	}
 	return nil
 }
+
+// losslessCodecs are the ffprobe names of the source codecs worth a lossless rendition.
+var losslessCodecs = []string{"flac", "alac", "wavpack", "ape", "tta", "mlp", "truehd", "tak"}
+
+// IsLosslessCodec reports whether codec, as named by ffprobe, is lossless. PCM of any layout counts.
+func IsLosslessCodec(codec string) bool {
+	return strings.HasPrefix(codec, "pcm_") || slices.Contains(losslessCodecs, codec)
+}
+
+// ProbeAudioCodec returns the ffprobe name of the codec of the first audio stream.
+func ProbeAudioCodec(ctx context.Context, inputPath string) (string, error) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return "", fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	args := []string{
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=codec_name",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		inputPath,
+	}
+
+	cmd := exec.CommandContext(ctx, "ffprobe", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("ffprobe command failed: %w: %s", err, stderr.String())
+	}
+
+	codec := strings.TrimSpace(stdout.String())
+	if codec == "" {
+		return "", fmt.Errorf("no audio stream in %s", filepath.Base(inputPath))
+	}
+	return codec, nil
+}
+
+// ProbeDuration returns the duration of the media file as reported by ffprobe.
+func ProbeDuration(ctx context.Context, inputPath string) (time.Duration, error) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return 0, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	args := []string{
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		inputPath,
+	}
+
+	cmd := exec.CommandContext(ctx, "ffprobe", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("ffprobe command failed: %w: %s", err, stderr.String())
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(stdout.String()), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration %q: %w", stdout.String(), err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
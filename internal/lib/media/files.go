@@ -1,10 +1,25 @@
 package media
 
 import (
+	"context"
 	"io"
 	"os"
+	"path/filepath"
+
+	"github.com/Sheridanlk/Music-Service/internal/storage"
 )
 
+// ObjectPutter stores objects, it is implemented by the MinIO storage.
+type ObjectPutter interface {
+	PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error
+}
+
+// ObjectRemover lists and deletes objects, it is implemented by the MinIO storage.
+type ObjectRemover interface {
+	ListObjects(ctx context.Context, bucketName, prefix string) ([]storage.ObjectInfo, error)
+	RemoveObjects(ctx context.Context, bucketName string, objectNames []string) error
+}
+
 // WriteToFile writes the content from the reader to a file at the specified path.
 func WriteToFile(path string, reader io.Reader) error {
 	file, err := os.Create(path)
@@ -36,3 +51,53 @@ func OpenFile(path string) (*os.File, int64, error) {
 
 	return f, stat.Size(), nil
 }
+
+// UploadDir uploads every file of dirPath to bucket under prefix, subdirectories are skipped.
+func UploadDir(ctx context.Context, putter ObjectPutter, bucket, dirPath, prefix string) error {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		localPath := filepath.Join(dirPath, entry.Name())
+		objectKey := prefix + entry.Name()
+
+		file, size, err := OpenFile(localPath)
+		if err != nil {
+			return err
+		}
+
+		ct := DetectContentType(entry.Name())
+
+		err = putter.PutObject(ctx, bucket, objectKey, file, size, ct)
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemovePrefix deletes every object stored in bucket under prefix.
+func RemovePrefix(ctx context.Context, remover ObjectRemover, bucket, prefix string) error {
+	objects, err := remover.ListObjects(ctx, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(objects))
+	for i, obj := range objects {
+		keys[i] = obj.Key
+	}
+
+	return remover.RemoveObjects(ctx, bucket, keys)
+}
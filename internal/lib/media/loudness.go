@@ -0,0 +1,120 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoudnessTarget is the loudness the loudnorm filter aims for, in LUFS, dBTP and LU.
+type LoudnessTarget struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+}
+
+// LoudnessMeasurement is the first loudnorm pass over a file. Offset and Threshold
+// are only needed to feed the second pass.
+type LoudnessMeasurement struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+	Threshold  float64
+	Offset     float64
+}
+
+// loudnormStats is the JSON block loudnorm prints with print_format=json. All values are strings.
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// MeasureLoudness runs the analysis pass of the loudnorm filter, which measures
+// integrated loudness, true peak and loudness range as defined by EBU R128.
+// Silent input yields -Inf loudness.
+func MeasureLoudness(ctx context.Context, inputPath string, target LoudnessTarget) (LoudnessMeasurement, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return LoudnessMeasurement{}, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-nostats",
+		"-i", inputPath,
+		"-vn",
+		"-af", fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:print_format=json", target.Integrated, target.TruePeak, target.Range),
+		"-f", "null",
+		"-",
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "ffmpeg", trace.WithAttributes(
+		attribute.String("ffmpeg.format", "loudnorm"),
+	))
+	defer span.End()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	metrics.FFmpegDuration.Observe(time.Since(start).Seconds())
+	tracing.RecordError(span, err)
+
+	if err != nil {
+		return LoudnessMeasurement{}, fmt.Errorf("ffmpeg command failed: %w: %s", err, stderr.String())
+	}
+
+	out := stderr.Bytes()
+	open, end := bytes.LastIndexByte(out, '{'), bytes.LastIndexByte(out, '}')
+	if open < 0 || end < open {
+		return LoudnessMeasurement{}, fmt.Errorf("loudnorm printed no statistics: %s", stderr.String())
+	}
+
+	var stats loudnormStats
+	if err := json.Unmarshal(out[open:end+1], &stats); err != nil {
+		return LoudnessMeasurement{}, fmt.Errorf("failed to parse loudnorm statistics: %w", err)
+	}
+
+	var m LoudnessMeasurement
+	for _, f := range []struct {
+		raw string
+		dst *float64
+	}{
+		{stats.InputI, &m.Integrated},
+		{stats.InputTP, &m.TruePeak},
+		{stats.InputLRA, &m.Range},
+		{stats.InputThresh, &m.Threshold},
+		{stats.TargetOffset, &m.Offset},
+	} {
+		v, err := strconv.ParseFloat(f.raw, 64)
+		if err != nil {
+			return LoudnessMeasurement{}, fmt.Errorf("failed to parse loudnorm value %q: %w", f.raw, err)
+		}
+		*f.dst = v
+	}
+
+	return m, nil
+}
+
+// LoudnormFilter returns the second pass filter that normalizes to target using the measurement,
+// in linear mode so the dynamics of the master are kept whenever the true peak allows it.
+func LoudnormFilter(target LoudnessTarget, m LoudnessMeasurement) string {
+	return fmt.Sprintf(
+		"loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:measured_I=%.2f:measured_TP=%.2f:measured_LRA=%.2f:measured_thresh=%.2f:offset=%.2f:linear=true",
+		target.Integrated, target.TruePeak, target.Range,
+		m.Integrated, m.TruePeak, m.Range, m.Threshold, m.Offset,
+	)
+}
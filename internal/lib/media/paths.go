@@ -1,11 +1,74 @@
 package media
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func GenerateTrackOriginKey(id int64, ext string) string {
 	return fmt.Sprintf("tracks/%d/source/original%s", id, ext)
 }
 
-func GenerateTrackHLSKey(id int64) string {
-	return fmt.Sprintf("tracks/%d/hls/aac_128/", id)
+// NewRun returns the id of a processing run. Renditions are stored under the run that produced them,
+// so segments cached as immutable are never replaced in place when a track is processed again.
+func NewRun() string {
+	return strconv.FormatInt(time.Now().UnixMilli(), 36)
+}
+
+// GenerateTrackHLSKey returns the prefix of the AAC rendition produced by run.
+func GenerateTrackHLSKey(id int64, run string) string {
+	return fmt.Sprintf("tracks/%d/hls/%s/aac_128/", id, run)
+}
+
+// GenerateTrackLosslessKey returns the prefix of the lossless rendition encoded with codec by run.
+func GenerateTrackLosslessKey(id int64, run, codec string) string {
+	return fmt.Sprintf("tracks/%d/hls/%s/%s/", id, run, codec)
+}
+
+// GenerateTrackPreviewKey returns the prefix of the preview rendition cut at start by run.
+func GenerateTrackPreviewKey(id int64, run string, start time.Duration) string {
+	return fmt.Sprintf("tracks/%d/hls/%s/preview_%d/", id, run, start.Milliseconds())
+}
+
+// ParseTrackKey returns the track ID of an object created by GenerateTrackOriginKey or GenerateTrackHLSKey.
+func ParseTrackKey(key string) (int64, bool) {
+	rest, ok := strings.CutPrefix(key, "tracks/")
+	if !ok {
+		return 0, false
+	}
+
+	idStr, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// GenerateTrackWaveformKey returns the key of the peaks at samplesPerPixel, ext is "json" or "dat".
+func GenerateTrackWaveformKey(id int64, samplesPerPixel int, ext string) string {
+	return fmt.Sprintf("tracks/%d/waveform/%d.%s", id, samplesPerPixel, ext)
+}
+
+// GenerateTrackCoverSourceKey returns the key of the cover uploaded with the track, kept next to the original.
+func GenerateTrackCoverSourceKey(id int64) string {
+	return fmt.Sprintf("tracks/%d/source/cover", id)
+}
+
+// GenerateTrackCoverKey returns the key of the square cover variant of size pixels, ext is "jpg" or "webp".
+func GenerateTrackCoverKey(id int64, size int, ext string) string {
+	return fmt.Sprintf("tracks/%d/cover/%d.%s", id, size, ext)
+}
+
+// GenerateTrackDownloadKey returns the key of the cached download transcoded to format. version changes
+// with the tags written to the file, so a download is never served with stale tags.
+func GenerateTrackDownloadKey(id int64, format, version string) string {
+	return fmt.Sprintf("tracks/%d/download/%s.%s", id, version, format)
 }
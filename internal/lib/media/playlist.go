@@ -0,0 +1,53 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var uriAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+// RewritePlaylist applies rewrite to every URI in an m3u8 playlist:
+// plain URI lines as well as URI attributes of tags such as #EXT-X-KEY and #EXT-X-MAP.
+func RewritePlaylist(r io.Reader, rewrite func(uri string) string) ([]byte, error) {
+	var out bytes.Buffer
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			line = uriAttr.ReplaceAllStringFunc(line, func(m string) string {
+				uri := uriAttr.FindStringSubmatch(m)[1]
+				return `URI="` + rewrite(uri) + `"`
+			})
+		default:
+			line = rewrite(trimmed)
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// AppendQuery appends a query parameter to a relative or absolute URI.
+func AppendQuery(uri, key, value string) string {
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
+	}
+	return uri + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,93 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Download formats. The original file is served as uploaded, the others are transcoded on demand.
+const (
+	DownloadOriginal = "original"
+	DownloadMP3      = "mp3"
+	DownloadFLAC     = "flac"
+	DownloadOpus     = "opus"
+)
+
+// TranscodeFormats are the download formats produced by the worker, the format is also the file extension.
+var TranscodeFormats = []string{DownloadMP3, DownloadFLAC, DownloadOpus}
+
+// Tags are written to a transcoded file. CoverPath is attached as the front cover when set,
+// Ogg Opus files are written without one because ffmpeg can't mux pictures into Ogg.
+type Tags struct {
+	Title     string
+	GainDB    *float64
+	CoverPath string
+}
+
+// Transcode encodes the audio of the input to a download file in format, dropping the tags of the source.
+// MP3 is VBR V0, Opus is 160k, FLAC keeps the sample rate and bit depth of the source.
+func Transcode(ctx context.Context, inputPath, outputPath, format string, tags Tags) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	cover := tags.CoverPath != "" && format != DownloadOpus
+
+	args := []string{"-y", "-i", inputPath}
+	if cover {
+		args = append(args, "-i", tags.CoverPath, "-map", "0:a:0", "-map", "1:v:0", "-c:v", "copy", "-disposition:v", "attached_pic")
+	} else {
+		args = append(args, "-map", "0:a:0")
+	}
+	args = append(args, "-map_metadata", "-1")
+
+	switch format {
+	case DownloadMP3:
+		args = append(args, "-c:a", "libmp3lame", "-q:a", "0", "-id3v2_version", "3")
+	case DownloadFLAC:
+		args = append(args, "-c:a", "flac")
+	case DownloadOpus:
+		args = append(args, "-c:a", "libopus", "-b:a", "160k")
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	if tags.Title != "" {
+		args = append(args, "-metadata", "title="+tags.Title)
+	}
+	if tags.GainDB != nil {
+		args = append(args, "-metadata", fmt.Sprintf("REPLAYGAIN_TRACK_GAIN=%.2f dB", *tags.GainDB))
+	}
+	if cover {
+		args = append(args, "-metadata:s:v", "comment=Cover (front)")
+	}
+	args = append(args, "-f", format, outputPath)
+
+	ctx, span := tracing.Tracer().Start(ctx, "ffmpeg", trace.WithAttributes(
+		attribute.String("ffmpeg.format", "transcode"),
+		attribute.String("ffmpeg.codec", format),
+	))
+	defer span.End()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	metrics.FFmpegDuration.Observe(time.Since(start).Seconds())
+	tracing.RecordError(span, err)
+
+	if err != nil {
+		return fmt.Errorf("ffmpeg command failed: %w: %s", err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// waveformVersion is the audiowaveform data format version. Version 2 adds the channel count.
+const waveformVersion = 2
+
+// Waveform holds mono min/max peak pairs with 8 bit resolution, in the layout produced by audiowaveform.
+type Waveform struct {
+	SampleRate      int
+	SamplesPerPixel int
+	Data            []int8
+}
+
+// Length is the number of min/max pairs.
+func (w Waveform) Length() int {
+	return len(w.Data) / 2
+}
+
+type waveformJSON struct {
+	Version         int    `json:"version"`
+	Channels        int    `json:"channels"`
+	SampleRate      int    `json:"sample_rate"`
+	SamplesPerPixel int    `json:"samples_per_pixel"`
+	Bits            int    `json:"bits"`
+	Length          int    `json:"length"`
+	Data            []int8 `json:"data"`
+}
+
+func (w Waveform) MarshalJSON() ([]byte, error) {
+	return json.Marshal(waveformJSON{
+		Version:         waveformVersion,
+		Channels:        1,
+		SampleRate:      w.SampleRate,
+		SamplesPerPixel: w.SamplesPerPixel,
+		Bits:            8,
+		Length:          w.Length(),
+		Data:            w.Data,
+	})
+}
+
+// MarshalBinary encodes the waveform as an audiowaveform .dat file.
+func (w Waveform) MarshalBinary() ([]byte, error) {
+	const flags8Bit = 1
+
+	var buf bytes.Buffer
+	header := []int32{waveformVersion, flags8Bit, int32(w.SampleRate), int32(w.SamplesPerPixel), int32(w.Length()), 1}
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, w.Data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ComputeWaveforms decodes the input to mono PCM at sampleRate and computes the peaks
+// for every samples-per-pixel value in one pass.
+func ComputeWaveforms(ctx context.Context, inputPath string, sampleRate int, samplesPerPixel []int) ([]Waveform, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	args := []string{
+		"-v", "error",
+		"-i", inputPath,
+		"-vn",
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", sampleRate),
+		"-f", "s16le",
+		"-acodec", "pcm_s16le",
+		"-",
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "ffmpeg", trace.WithAttributes(
+		attribute.String("ffmpeg.format", "pcm"),
+		attribute.Int("ffmpeg.sample_rate", sampleRate),
+	))
+	defer span.End()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't open ffmpeg output: %w", err)
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("ffmpeg command failed: %w", err)
+	}
+
+	peaks := make([]*peakBuilder, len(samplesPerPixel))
+	for i, spp := range samplesPerPixel {
+		peaks[i] = &peakBuilder{samplesPerPixel: spp}
+	}
+
+	readErr := readSamples(bufio.NewReader(stdout), func(sample int16) {
+		for _, p := range peaks {
+			p.add(sample)
+		}
+	})
+
+	err = cmd.Wait()
+	metrics.FFmpegDuration.Observe(time.Since(start).Seconds())
+	if err == nil {
+		err = readErr
+	}
+	tracing.RecordError(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg command failed: %w: %s", err, stderr.String())
+	}
+
+	waveforms := make([]Waveform, len(peaks))
+	for i, p := range peaks {
+		waveforms[i] = Waveform{
+			SampleRate:      sampleRate,
+			SamplesPerPixel: p.samplesPerPixel,
+			Data:            p.finish(),
+		}
+	}
+
+	return waveforms, nil
+}
+
+func readSamples(r io.Reader, fn func(sample int16)) error {
+	buf := make([]byte, 32*1024)
+	var carry []byte
+
+	for {
+		n, err := r.Read(buf)
+		data := append(carry, buf[:n]...)
+
+		i := 0
+		for ; i+1 < len(data); i += 2 {
+			fn(int16(binary.LittleEndian.Uint16(data[i:])))
+		}
+		carry = append(carry[:0], data[i:]...)
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type peakBuilder struct {
+	samplesPerPixel int
+
+	count    int
+	min, max int16
+	data     []int8
+}
+
+func (p *peakBuilder) add(sample int16) {
+	if p.count == 0 {
+		p.min, p.max = sample, sample
+	}
+	p.min = min(p.min, sample)
+	p.max = max(p.max, sample)
+	p.count++
+
+	if p.count == p.samplesPerPixel {
+		p.flush()
+	}
+}
+
+func (p *peakBuilder) flush() {
+	p.data = append(p.data, to8Bit(p.min), to8Bit(p.max))
+	p.count = 0
+}
+
+func (p *peakBuilder) finish() []int8 {
+	if p.count > 0 {
+		p.flush()
+	}
+	return p.data
+}
+
+// to8Bit keeps the high byte of the sample, as audiowaveform does for 8 bit output.
+func to8Bit(sample int16) int8 {
+	return int8(sample >> 8)
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const collectTimeout = 5 * time.Second
+
+var (
+	tracksDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "tracks"),
+		"Current number of tracks by status.",
+		[]string{"status"}, nil,
+	)
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "messages"),
+		"Messages waiting in the queue.",
+		[]string{"queue"}, nil,
+	)
+)
+
+// TrackCounter counts tracks grouped by status.
+type TrackCounter interface {
+	CountTracksByStatus(ctx context.Context) (map[string]int, error)
+}
+
+type trackCollector struct {
+	log     *slog.Logger
+	counter TrackCounter
+}
+
+// NewTrackCollector reports track counts per status, queried on every scrape.
+func NewTrackCollector(log *slog.Logger, counter TrackCounter) prometheus.Collector {
+	return &trackCollector{log: log, counter: counter}
+}
+
+func (c *trackCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tracksDesc
+}
+
+func (c *trackCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountTracksByStatus(ctx)
+	if err != nil {
+		c.log.Warn("failed to count tracks", slog.String("error", err.Error()))
+		return
+	}
+
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(tracksDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
+
+// QueueInspector reports how many messages are waiting in a queue.
+type QueueInspector interface {
+	QueueDepth(queue string) (int, error)
+}
+
+type queueCollector struct {
+	log       *slog.Logger
+	inspector QueueInspector
+	queues    []string
+}
+
+// NewQueueCollector reports the depth of every queue under the queue label, queried on every scrape.
+func NewQueueCollector(log *slog.Logger, inspector QueueInspector, queues ...string) prometheus.Collector {
+	return &queueCollector{log: log, inspector: inspector, queues: queues}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, queue := range c.queues {
+		depth, err := c.inspector.QueueDepth(queue)
+		if err != nil {
+			c.log.Warn("failed to inspect queue", slog.String("queue", queue), slog.String("error", err.Error()))
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth), queue)
+	}
+}
@@ -0,0 +1,105 @@
+// Package metrics holds the Prometheus collectors shared by the service and the worker.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "music"
+
+// Outcome label values.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	UploadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes of original tracks stored.",
+	})
+
+	StreamBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_bytes_total",
+		Help:      "Bytes of HLS files served.",
+	})
+
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "job_duration_seconds",
+		Help:      "Track processing time by task and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"task", "outcome"})
+
+	FFmpegDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "ffmpeg_duration_seconds",
+		Help:      "Runtime of ffmpeg invocations.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	})
+
+	MinIODuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "minio",
+		Name:      "request_duration_seconds",
+		Help:      "MinIO call latency by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	PostgresDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "query_duration_seconds",
+		Help:      "Postgres query latency by statement type and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func Register(collectors ...prometheus.Collector) error {
+	for _, c := range collectors {
+		if err := prometheus.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
+
+// ObserveMinIO records the latency of a MinIO call started at start.
+func ObserveMinIO(operation string, start time.Time, err error) {
+	MinIODuration.WithLabelValues(operation, Outcome(err)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,51 @@
+// Package ratelimit implements token buckets shared by the in-memory and Postgres stores.
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit allows Burst requests at once, the bucket refills evenly over Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Period > 0
+}
+
+// Result describes the bucket state after a request was counted.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, zero when allowed
+}
+
+// Take refills the bucket holding tokens since last and tries to take one token from it.
+// It returns the new amount of tokens together with the result.
+func Take(tokens float64, last, now time.Time, limit Limit) (float64, Result) {
+	perToken := limit.Period / time.Duration(limit.Burst)
+
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens += float64(elapsed) / float64(perToken)
+	}
+	tokens = math.Min(tokens, float64(limit.Burst))
+
+	res := Result{Limit: limit.Burst}
+
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration((float64(limit.Burst) - tokens) * float64(perToken))
+
+	return tokens, res
+}
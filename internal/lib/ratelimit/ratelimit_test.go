@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Burst: 4, Period: 4 * time.Second}
+	last := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		tokens        float64
+		elapsed       time.Duration
+		wantTokens    float64
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{name: "full bucket", tokens: 4, wantTokens: 3, wantAllowed: true, wantRemaining: 3, wantReset: time.Second},
+		{name: "last token", tokens: 1, wantTokens: 0, wantAllowed: true, wantRemaining: 0, wantReset: 4 * time.Second},
+		{name: "empty bucket", tokens: 0, wantTokens: 0, wantRetry: time.Second, wantReset: 4 * time.Second},
+		{name: "partly refilled", tokens: 0, elapsed: 500 * time.Millisecond, wantTokens: 0.5, wantRetry: 500 * time.Millisecond, wantReset: 3500 * time.Millisecond},
+		{name: "refill after one period", tokens: 0, elapsed: time.Second, wantTokens: 0, wantAllowed: true, wantReset: 4 * time.Second},
+		{name: "refill after idle", tokens: 0, elapsed: 3 * time.Second, wantTokens: 2, wantAllowed: true, wantRemaining: 2, wantReset: 2 * time.Second},
+		{name: "burst cap after long idle", tokens: 1, elapsed: time.Hour, wantTokens: 3, wantAllowed: true, wantRemaining: 3, wantReset: time.Second},
+		{name: "clock going back", tokens: 2, elapsed: -time.Minute, wantTokens: 1, wantAllowed: true, wantRemaining: 1, wantReset: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, res := Take(tt.tokens, last, last.Add(tt.elapsed), limit)
+			if tokens != tt.wantTokens {
+				t.Fatalf("Take() tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			want := Result{
+				Allowed:    tt.wantAllowed,
+				Limit:      limit.Burst,
+				Remaining:  tt.wantRemaining,
+				Reset:      tt.wantReset,
+				RetryAfter: tt.wantRetry,
+			}
+			if res != want {
+				t.Fatalf("Take() = %+v, want %+v", res, want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreBurst(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 3, Period: time.Hour}
+
+	for i := range limit.Burst {
+		res, err := store.TakeToken(t.Context(), "client", limit)
+		if err != nil {
+			t.Fatalf("TakeToken: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d was limited within the burst", i+1)
+		}
+	}
+
+	res, err := store.TakeToken(t.Context(), "client", limit)
+	if err != nil {
+		t.Fatalf("TakeToken: %v", err)
+	}
+	if res.Allowed || res.RetryAfter <= 0 {
+		t.Fatalf("request over the burst = %+v, want limited with a retry delay", res)
+	}
+
+	res, err = store.TakeToken(t.Context(), "other", limit)
+	if err != nil {
+		t.Fatalf("TakeToken: %v", err)
+	}
+	if !res.Allowed {
+		t.Fatal("another client shares the bucket")
+	}
+}
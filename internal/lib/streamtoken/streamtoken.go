@@ -0,0 +1,180 @@
+package streamtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid stream token")
+	ErrTokenExpired = errors.New("stream token expired")
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrWrongUser    = errors.New("stream token issued to another user")
+)
+
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+type Claims struct {
+	KeyID     string `json:"kid"`
+	TrackID   int64  `json:"tid"`
+	UserID    string `json:"uid,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer issues and verifies HMAC-SHA256 stream tokens.
+// Tokens are always signed with the active key, while every configured key is accepted for verification,
+// so a key can be rotated by adding a new one, making it active and removing the old one after the token TTL.
+type Signer struct {
+	keys      map[string][]byte
+	activeKey string
+	ttl       time.Duration
+}
+
+func New(keys []Key, activeKey string, ttl time.Duration) (*Signer, error) {
+	const op = "streamtoken.New"
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%s: ttl must be positive", op)
+	}
+
+	s := &Signer{
+		keys:      make(map[string][]byte, len(keys)),
+		activeKey: activeKey,
+		ttl:       ttl,
+	}
+
+	for _, k := range keys {
+		if k.ID == "" || len(k.Secret) == 0 {
+			return nil, fmt.Errorf("%s: key id and secret must not be empty", op)
+		}
+		s.keys[k.ID] = k.Secret
+	}
+
+	if _, ok := s.keys[activeKey]; !ok {
+		return nil, fmt.Errorf("%s: active key %q: %w", op, activeKey, ErrUnknownKey)
+	}
+
+	return s, nil
+}
+
+// ParseKeys parses a comma-separated list of id:secret pairs.
+func ParseKeys(raw string) ([]Key, error) {
+	const op = "streamtoken.ParseKeys"
+
+	var keys []Key
+
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("%s: malformed key %q", op, id)
+		}
+
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys configured", op)
+	}
+
+	return keys, nil
+}
+
+// Subject returns the user id claim of tokens issued to the user, empty for anonymous listeners (id 0).
+func Subject(userID int64) string {
+	if userID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(userID, 10)
+}
+
+// Sign issues a token for the track. The token is bound to the user when userID is not empty.
+func (s *Signer) Sign(trackID int64, userID string) (string, error) {
+	const op = "streamtoken.Sign"
+
+	claims := Claims{
+		KeyID:     s.activeKey,
+		TrackID:   trackID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := s.mac(s.keys[s.activeKey], encoded)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac), nil
+}
+
+// Verify checks the signature and expiry of the token and that it was issued for the track to the user,
+// an empty userID stands for an anonymous listener.
+func (s *Signer) Verify(token string, trackID int64, userID string) (Claims, error) {
+	const op = "streamtoken.Verify"
+
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	secret, ok := s.keys[claims.KeyID]
+	if !ok {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrUnknownKey)
+	}
+
+	if !hmac.Equal(mac, s.mac(secret, encoded)) {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if claims.TrackID != trackID {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if time.Now().Unix() > claims.ExpiresAt {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrTokenExpired)
+	}
+
+	if claims.UserID != userID {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrWrongUser)
+	}
+
+	return claims, nil
+}
+
+func (s *Signer) mac(secret []byte, payload string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
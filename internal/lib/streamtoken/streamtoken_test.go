@@ -0,0 +1,137 @@
+package streamtoken
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustSigner(t *testing.T, keys []Key, active string, ttl time.Duration) *Signer {
+	t.Helper()
+
+	s, err := New(keys, active, ttl)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+// forge re-signs the claims with the secret, as a signer holding that key would.
+func forge(t *testing.T, claims Claims, secret []byte) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := (&Signer{}).mac(secret, encoded)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac)
+}
+
+func TestVerify(t *testing.T) {
+	oldKey := Key{ID: "k1", Secret: []byte("old-secret")}
+	newKey := Key{ID: "k2", Secret: []byte("new-secret")}
+
+	oldSigner := mustSigner(t, []Key{oldKey}, "k1", time.Minute)
+	rotated := mustSigner(t, []Key{oldKey, newKey}, "k2", time.Minute)
+	retired := mustSigner(t, []Key{newKey}, "k2", time.Minute)
+
+	valid, err := oldSigner.Sign(7, "42")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	anonymous, err := oldSigner.Sign(7, "")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	expired := forge(t, Claims{KeyID: "k1", TrackID: 7, UserID: "42", ExpiresAt: time.Now().Add(-time.Second).Unix()}, oldKey.Secret)
+	wrongSecret := forge(t, Claims{KeyID: "k1", TrackID: 7, UserID: "42", ExpiresAt: time.Now().Add(time.Minute).Unix()}, []byte("guessed"))
+
+	encoded, sig, _ := strings.Cut(valid, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(encoded)
+	tamperedPayload := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), `"tid":7`, `"tid":8`, 1))) + "." + sig
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		token   string
+		trackID int64
+		userID  string
+		wantErr error
+	}{
+		{name: "valid", signer: oldSigner, token: valid, trackID: 7, userID: "42"},
+		{name: "anonymous", signer: oldSigner, token: anonymous, trackID: 7, userID: ""},
+		{name: "accepted after rotation", signer: rotated, token: valid, trackID: 7, userID: "42"},
+		{name: "expired", signer: oldSigner, token: expired, trackID: 7, userID: "42", wantErr: ErrTokenExpired},
+		{name: "wrong track", signer: oldSigner, token: valid, trackID: 8, userID: "42", wantErr: ErrInvalidToken},
+		{name: "wrong user", signer: oldSigner, token: valid, trackID: 7, userID: "43", wantErr: ErrWrongUser},
+		{name: "anonymous with user token", signer: oldSigner, token: valid, trackID: 7, userID: "", wantErr: ErrWrongUser},
+		{name: "user with anonymous token", signer: oldSigner, token: anonymous, trackID: 7, userID: "42", wantErr: ErrWrongUser},
+		{name: "retired key", signer: retired, token: valid, trackID: 7, userID: "42", wantErr: ErrUnknownKey},
+		{name: "wrong secret", signer: oldSigner, token: wrongSecret, trackID: 7, userID: "42", wantErr: ErrInvalidToken},
+		{name: "tampered payload", signer: oldSigner, token: tamperedPayload, trackID: 8, userID: "42", wantErr: ErrInvalidToken},
+		{name: "tampered signature", signer: oldSigner, token: valid + "A", trackID: 7, userID: "42", wantErr: ErrInvalidToken},
+		{name: "no signature", signer: oldSigner, token: encoded, trackID: 7, userID: "42", wantErr: ErrInvalidToken},
+		{name: "garbage", signer: oldSigner, token: "not a token.at all", trackID: 7, userID: "42", wantErr: ErrInvalidToken},
+		{name: "empty", signer: oldSigner, token: "", trackID: 7, userID: "", wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.signer.Verify(tt.token, tt.trackID, tt.userID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.TrackID != tt.trackID || claims.UserID != tt.userID {
+				t.Fatalf("Verify() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestSignUsesActiveKey(t *testing.T) {
+	s := mustSigner(t, []Key{{ID: "k1", Secret: []byte("a")}, {ID: "k2", Secret: []byte("b")}}, "k2", time.Minute)
+
+	token, err := s.Sign(1, "")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	claims, err := s.Verify(token, 1, "")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.KeyID != "k2" {
+		t.Fatalf("KeyID = %q, want k2", claims.KeyID)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		userID int64
+		want   string
+	}{
+		{userID: 0, want: ""},
+		{userID: 42, want: "42"},
+	}
+
+	for _, tt := range tests {
+		if got := Subject(tt.userID); got != tt.want {
+			t.Errorf("Subject(%d) = %q, want %q", tt.userID, got, tt.want)
+		}
+	}
+}
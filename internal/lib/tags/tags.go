@@ -0,0 +1,58 @@
+// Package tags normalizes the free-form tags users put on tracks.
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxPerTrack bounds how many tags a track may carry.
+	MaxPerTrack = 10
+	// MaxLength bounds a tag in characters.
+	MaxLength = 32
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Normalize lowercases the tags, collapses inner whitespace into single dashes and drops blanks and duplicates.
+// Tags may only hold letters, digits and dashes once normalized.
+func Normalize(raw []string) ([]string, error) {
+	result := make([]string, 0, len(raw))
+
+	for _, tag := range raw {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag == "" || slices.Contains(result, tag) {
+			continue
+		}
+
+		if utf8.RuneCountInString(tag) > MaxLength {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, tag, MaxLength)
+		}
+		if strings.ContainsFunc(tag, func(r rune) bool { return r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			return nil, fmt.Errorf("%w: %q may only hold letters, digits and dashes", ErrInvalidTag, tag)
+		}
+
+		result = append(result, tag)
+	}
+
+	if len(result) > MaxPerTrack {
+		return nil, fmt.Errorf("%w: more than %d tags", ErrInvalidTag, MaxPerTrack)
+	}
+
+	slices.Sort(result)
+
+	return result, nil
+}
+
+// Split splits a comma separated list of tags as sent in forms and query strings.
+func Split(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
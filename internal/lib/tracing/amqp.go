@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+)
+
+// amqpCarrier adapts message headers to the propagation API.
+type amqpCarrier amqp.Table
+
+func (c amqpCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c amqpCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c amqpCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectAMQP writes the trace context of ctx into the message headers.
+func InjectAMQP(ctx context.Context, headers amqp.Table) {
+	otel.GetTextMapPropagator().Inject(ctx, amqpCarrier(headers))
+}
+
+// ExtractAMQP returns ctx continued with the trace context from the message headers.
+func ExtractAMQP(ctx context.Context, headers amqp.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, amqpCarrier(headers))
+}
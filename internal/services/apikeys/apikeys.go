@@ -0,0 +1,178 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/apikey"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// lastUsedResolution limits how often the last used timestamp of a key is written.
+const lastUsedResolution = time.Minute
+
+var (
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrKeyNotFound   = errors.New("api key not found")
+	ErrInvalidScopes = errors.New("invalid scopes")
+)
+
+type APIKeyService struct {
+	log *slog.Logger
+
+	keyStore     KeyStore
+	userProvider UserProvider
+}
+
+type KeyStore interface {
+	SaveAPIKey(ctx context.Context, userID int64, name, prefix string, keyHash []byte, scopes []string) (models.APIKey, error)
+	APIKeyByHash(ctx context.Context, keyHash []byte) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID int64, limit int, after *models.Cursor) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64, userID int64) error
+	TouchAPIKey(ctx context.Context, id int64) error
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, id int64) (models.User, error)
+}
+
+func New(log *slog.Logger, keyStore KeyStore, userProvider UserProvider) *APIKeyService {
+	return &APIKeyService{
+		log:          log,
+		keyStore:     keyStore,
+		userProvider: userProvider,
+	}
+}
+
+// CreateKey issues a key for the user. The plain key is returned only once and never stored.
+func (s *APIKeyService) CreateKey(ctx context.Context, user models.User, name string, scopes []string) (models.APIKey, string, error) {
+	const op = "apikeys.CreateKey"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", user.ID),
+	)
+
+	if err := authz.CanManageAPIKeys(user); err != nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(scopes) == 0 {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, ErrInvalidScopes)
+	}
+	for _, scope := range scopes {
+		if !authz.ValidScope(scope) {
+			return models.APIKey{}, "", fmt.Errorf("%s: %w: %s", op, ErrInvalidScopes, scope)
+		}
+		if err := authz.CanGrantScope(user, scope); err != nil {
+			return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	raw, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: failed to generate key: %w", op, err)
+	}
+
+	key, err := s.keyStore.SaveAPIKey(ctx, user.ID, name, prefix, hash, scopes)
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: failed to save key: %w", op, err)
+	}
+
+	log.Info("api key created", slog.Int64("key_id", key.ID))
+
+	return key, raw, nil
+}
+
+// ListKeys returns a page of up to limit keys of the user, newest first, and the cursor of the next page,
+// nil on the last one.
+func (s *APIKeyService) ListKeys(ctx context.Context, user models.User, limit int, after *models.Cursor) ([]models.APIKey, *models.Cursor, error) {
+	const op = "apikeys.ListKeys"
+
+	if err := authz.CanManageAPIKeys(user); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// One more key than asked tells whether there is a next page.
+	keys, err := s.keyStore.ListAPIKeys(ctx, user.ID, limit+1, after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to list keys: %w", op, err)
+	}
+
+	if len(keys) <= limit {
+		return keys, nil, nil
+	}
+
+	keys = keys[:limit]
+	last := keys[limit-1]
+
+	return keys, &models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, user models.User, id int64) error {
+	const op = "apikeys.RevokeKey"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", user.ID),
+		slog.Int64("key_id", id),
+	)
+
+	if err := authz.CanManageAPIKeys(user); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := s.keyStore.RevokeAPIKey(ctx, id, user.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to revoke key: %w", op, err)
+	}
+
+	log.Info("api key revoked")
+
+	return nil
+}
+
+// AuthenticateAPIKey resolves the key to its owner, limited to the scopes of the key.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, raw string) (models.User, error) {
+	const op = "apikeys.AuthenticateAPIKey"
+
+	key, err := s.keyStore.APIKeyByHash(ctx, apikey.Hash(raw))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidKey)
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: failed to get key: %w", op, err)
+	}
+
+	if key.RevokedAt != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidKey)
+	}
+
+	user, err := s.userProvider.UserByID(ctx, key.UserID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: failed to get key owner: %w", op, err)
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > lastUsedResolution {
+		if err := s.keyStore.TouchAPIKey(ctx, key.ID); err != nil {
+			s.log.Warn("failed to update api key last used", slog.Int64("key_id", key.ID), slog.String("error", err.Error()))
+		}
+	}
+
+	user.PasswordHash = nil
+	user.Scopes = key.Scopes
+	if user.Scopes == nil {
+		user.Scopes = []string{}
+	}
+	user.APIKeyID = key.ID
+
+	return user, nil
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/jwt"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+type Auth struct {
+	log *slog.Logger
+
+	userSaver    UserSaver
+	userProvider UserProvider
+	tokenStore   RefreshTokenStore
+
+	secret      string
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	defaultRole string
+}
+
+type UserSaver interface {
+	SaveUser(ctx context.Context, email string, passwordHash []byte, role string) (int64, error)
+	SetUserRole(ctx context.Context, id int64, role string) error
+}
+
+type UserProvider interface {
+	UserByEmail(ctx context.Context, email string) (models.User, error)
+	UserByID(ctx context.Context, id int64) (models.User, error)
+}
+
+type RefreshTokenStore interface {
+	SaveRefreshToken(ctx context.Context, userID int64, tokenHash []byte, expiresAt time.Time) error
+	RefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id int64) error
+}
+
+func New(
+	log *slog.Logger,
+	userSaver UserSaver,
+	userProvider UserProvider,
+	tokenStore RefreshTokenStore,
+	secret string,
+	accessTTL time.Duration,
+	refreshTTL time.Duration,
+	defaultRole string,
+) *Auth {
+	return &Auth{
+		log:          log,
+		userSaver:    userSaver,
+		userProvider: userProvider,
+		tokenStore:   tokenStore,
+		secret:       secret,
+		accessTTL:    accessTTL,
+		refreshTTL:   refreshTTL,
+		defaultRole:  defaultRole,
+	}
+}
+
+func (a *Auth) Register(ctx context.Context, email string, password string) (int64, error) {
+	const op = "auth.Register"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("registering user")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to hash password: %w", op, err)
+	}
+
+	id, err := a.userSaver.SaveUser(ctx, normalizeEmail(email), hash, a.defaultRole)
+	if errors.Is(err, storage.ErrUserExists) {
+		return 0, fmt.Errorf("%s: %w", op, ErrUserExists)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to save user: %w", op, err)
+	}
+
+	log.Info("user registered", slog.Int64("user_id", id))
+
+	return id, nil
+}
+
+func (a *Auth) Login(ctx context.Context, email string, password string) (models.TokenPair, error) {
+	const op = "auth.Login"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := a.userProvider.UserByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: failed to get user: %w", op, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	pair, err := a.issueTokens(ctx, user)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in", slog.Int64("user_id", user.ID))
+
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The old refresh token is revoked.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error) {
+	const op = "auth.Refresh"
+
+	token, err := a.tokenStore.RefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: failed to get refresh token: %w", op, err)
+	}
+
+	if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	err = a.tokenStore.RevokeRefreshToken(ctx, token.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: failed to revoke refresh token: %w", op, err)
+	}
+
+	user, err := a.userProvider.UserByID(ctx, token.UserID)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: failed to get user: %w", op, err)
+	}
+
+	pair, err := a.issueTokens(ctx, user)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pair, nil
+}
+
+func (a *Auth) Logout(ctx context.Context, refreshToken string) error {
+	const op = "auth.Logout"
+
+	token, err := a.tokenStore.RefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to get refresh token: %w", op, err)
+	}
+
+	if err := a.tokenStore.RevokeRefreshToken(ctx, token.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: failed to revoke refresh token: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetRole changes the role of a user. Only admins may do it, the change applies to tokens issued afterwards.
+func (a *Auth) SetRole(ctx context.Context, actor models.User, userID int64, role string) error {
+	const op = "auth.SetRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.ID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := authz.CanAdminister(actor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !authz.ValidRole(role) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidRole)
+	}
+
+	err := a.userSaver.SetUserRole(ctx, userID, role)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to set role: %w", op, err)
+	}
+
+	log.Info("user role changed", slog.String("role", role))
+
+	return nil
+}
+
+// ParseAccessToken returns the user the access token was issued for.
+func (a *Auth) ParseAccessToken(token string) (models.User, error) {
+	const op = "auth.ParseAccessToken"
+
+	user, err := jwt.ParseToken(token, a.secret)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	return user, nil
+}
+
+func (a *Auth) issueTokens(ctx context.Context, user models.User) (models.TokenPair, error) {
+	access, expiresAt, err := jwt.NewToken(user, a.secret, a.accessTTL)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return models.TokenPair{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(raw)
+	refreshExpiresAt := time.Now().Add(a.refreshTTL)
+
+	if err := a.tokenStore.SaveRefreshToken(ctx, user.ID, hashToken(refresh), refreshExpiresAt); err != nil {
+		return models.TokenPair{}, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return models.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,213 @@
+package genres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+var (
+	ErrGenreNotFound  = errors.New("genre not found")
+	ErrParentNotFound = errors.New("parent genre not found")
+	ErrGenreExists    = errors.New("genre already exists")
+	ErrGenreInUse     = errors.New("genre has subgenres")
+	ErrInvalidGenre   = errors.New("invalid genre")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type GenreService struct {
+	log *slog.Logger
+
+	genreStore GenreStore
+}
+
+type GenreStore interface {
+	ListGenres(ctx context.Context, limit int, after *models.Cursor) ([]models.Genre, error)
+	AllGenres(ctx context.Context) ([]models.Genre, error)
+	SaveGenre(ctx context.Context, genre models.Genre) (int64, error)
+	UpdateGenre(ctx context.Context, genre models.Genre) error
+	DeleteGenre(ctx context.Context, id int64) error
+}
+
+func New(log *slog.Logger, genreStore GenreStore) *GenreService {
+	return &GenreService{
+		log:        log,
+		genreStore: genreStore,
+	}
+}
+
+// ListGenres returns a page of up to limit genres of the tree ordered by name and the cursor of the next page,
+// nil on the last one. Anyone may read it.
+func (s *GenreService) ListGenres(ctx context.Context, limit int, after *models.Cursor) ([]models.Genre, *models.Cursor, error) {
+	const op = "genres.ListGenres"
+
+	// One more genre than asked tells whether there is a next page.
+	genres, err := s.genreStore.ListGenres(ctx, limit+1, after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to list genres: %w", op, err)
+	}
+
+	if len(genres) <= limit {
+		return genres, nil, nil
+	}
+
+	genres = genres[:limit]
+	last := genres[limit-1]
+
+	return genres, &models.Cursor{Name: last.Name, ID: last.ID}, nil
+}
+
+func (s *GenreService) CreateGenre(ctx context.Context, actor models.User, genre models.Genre) (int64, error) {
+	const op = "genres.CreateGenre"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.ID),
+		slog.String("slug", genre.Slug),
+	)
+
+	if err := authz.CanAdminister(actor); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	genre, err := normalize(genre)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := s.genreStore.SaveGenre(ctx, genre)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storeError(err, ErrParentNotFound))
+	}
+
+	log.Info("genre created", slog.Int64("genre_id", id))
+
+	return id, nil
+}
+
+// UpdateGenre renames or moves a genre. A genre can't be moved under itself or one of its subgenres.
+func (s *GenreService) UpdateGenre(ctx context.Context, actor models.User, genre models.Genre) error {
+	const op = "genres.UpdateGenre"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.ID),
+		slog.Int64("genre_id", genre.ID),
+	)
+
+	if err := authz.CanAdminister(actor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	genre, err := normalize(genre)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if genre.ParentID != nil {
+		all, err := s.genreStore.AllGenres(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: failed to list genres: %w", op, err)
+		}
+		if err := checkParent(all, genre); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := s.genreStore.UpdateGenre(ctx, genre); err != nil {
+		if errors.Is(err, storage.ErrNotFound) && genre.ParentID == nil {
+			return fmt.Errorf("%s: %w", op, ErrGenreNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, storeError(err, ErrParentNotFound))
+	}
+
+	log.Info("genre updated")
+
+	return nil
+}
+
+// DeleteGenre removes a genre without subgenres, its tracks are left without a genre.
+func (s *GenreService) DeleteGenre(ctx context.Context, actor models.User, id int64) error {
+	const op = "genres.DeleteGenre"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.ID),
+		slog.Int64("genre_id", id),
+	)
+
+	if err := authz.CanAdminister(actor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.genreStore.DeleteGenre(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, storeError(err, ErrGenreNotFound))
+	}
+
+	log.Info("genre deleted")
+
+	return nil
+}
+
+func normalize(genre models.Genre) (models.Genre, error) {
+	genre.Slug = strings.TrimSpace(genre.Slug)
+	genre.Name = strings.TrimSpace(genre.Name)
+
+	if !slugPattern.MatchString(genre.Slug) {
+		return genre, fmt.Errorf("%w: slug must be lowercase letters and digits separated by dashes", ErrInvalidGenre)
+	}
+	if genre.Name == "" {
+		return genre, fmt.Errorf("%w: name must not be empty", ErrInvalidGenre)
+	}
+
+	return genre, nil
+}
+
+// checkParent makes sure the new parent exists and isn't the genre or one of its subgenres.
+func checkParent(all []models.Genre, genre models.Genre) error {
+	parents := make(map[int64]*int64, len(all))
+	found := false
+	for _, g := range all {
+		parents[g.ID] = g.ParentID
+		found = found || g.ID == genre.ID
+	}
+
+	if !found {
+		return ErrGenreNotFound
+	}
+
+	for id := genre.ParentID; id != nil; {
+		if *id == genre.ID {
+			return fmt.Errorf("%w: a genre can't be moved under itself", ErrInvalidGenre)
+		}
+		parent, ok := parents[*id]
+		if !ok {
+			return ErrParentNotFound
+		}
+		id = parent
+	}
+
+	return nil
+}
+
+// storeError maps storage errors, notFound is what storage.ErrNotFound means to the caller.
+func storeError(err error, notFound error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return notFound
+	case errors.Is(err, storage.ErrGenreExists):
+		return ErrGenreExists
+	case errors.Is(err, storage.ErrGenreInUse):
+		return ErrGenreInUse
+	default:
+		return err
+	}
+}
@@ -0,0 +1,282 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrFileTooLarge  = errors.New("file too large")
+	ErrTooLong       = errors.New("track too long")
+	ErrUnknownPlan   = errors.New("unknown plan")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrNotEntitled   = errors.New("not included in plan")
+)
+
+type QuotaService struct {
+	log *slog.Logger
+
+	quotaStore    QuotaStore
+	trackProvider TrackProvider
+
+	plans       map[string]models.Quota
+	defaultPlan string
+}
+
+type QuotaStore interface {
+	UserPlan(ctx context.Context, userID int64) (string, error)
+	SetUserPlan(ctx context.Context, userID int64, plan string) error
+	UserQuotaOverride(ctx context.Context, userID int64) (models.QuotaOverride, error)
+	SetUserQuotaOverride(ctx context.Context, userID int64, override models.QuotaOverride) error
+	StorageUsage(ctx context.Context, userID int64) (models.StorageUsage, error)
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+}
+
+// Report is the quota state of a user as shown to the user.
+type Report struct {
+	Plan   string
+	Limits models.Quota
+	Usage  models.StorageUsage
+}
+
+// New creates a quota service. Users on a plan missing from plans get the limits of defaultPlan,
+// and no limits at all when that one is missing too.
+func New(log *slog.Logger, quotaStore QuotaStore, trackProvider TrackProvider, plans map[string]models.Quota, defaultPlan string) *QuotaService {
+	return &QuotaService{
+		log:           log,
+		quotaStore:    quotaStore,
+		trackProvider: trackProvider,
+		plans:         plans,
+		defaultPlan:   defaultPlan,
+	}
+}
+
+// Limits combines the plan of the user with the per-user overrides.
+func (s *QuotaService) Limits(ctx context.Context, userID int64) (string, models.Quota, error) {
+	plan, err := s.quotaStore.UserPlan(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", models.Quota{}, ErrUserNotFound
+	}
+	if err != nil {
+		return "", models.Quota{}, fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	limits, ok := s.plans[plan]
+	if !ok {
+		limits = s.plans[s.defaultPlan]
+	}
+
+	override, err := s.quotaStore.UserQuotaOverride(ctx, userID)
+	if err != nil {
+		return "", models.Quota{}, fmt.Errorf("failed to get quota override: %w", err)
+	}
+
+	if override.MaxBytes != nil {
+		limits.MaxBytes = *override.MaxBytes
+	}
+	if override.MaxTracks != nil {
+		limits.MaxTracks = *override.MaxTracks
+	}
+	if override.MaxFileBytes != nil {
+		limits.MaxFileBytes = *override.MaxFileBytes
+	}
+	if override.MaxDuration != nil {
+		limits.MaxDuration = *override.MaxDuration
+	}
+
+	return plan, limits, nil
+}
+
+func (s *QuotaService) Usage(ctx context.Context, user models.User) (Report, error) {
+	const op = "quota.Usage"
+
+	plan, limits, err := s.Limits(ctx, user.ID)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	usage, err := s.quotaStore.StorageUsage(ctx, user.ID)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: failed to get usage: %w", op, err)
+	}
+
+	return Report{
+		Plan:   plan,
+		Limits: limits,
+		Usage:  usage,
+	}, nil
+}
+
+// CheckUpload is called before a new track is stored. size is zero when it is not known yet.
+// It returns the largest file the user may upload right now, zero means unlimited.
+func (s *QuotaService) CheckUpload(ctx context.Context, user models.User, size int64) (int64, error) {
+	const op = "quota.CheckUpload"
+
+	_, limits, err := s.Limits(ctx, user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	usage, err := s.quotaStore.StorageUsage(ctx, user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get usage: %w", op, err)
+	}
+
+	if limits.MaxTracks > 0 && usage.Tracks >= limits.MaxTracks {
+		return 0, fmt.Errorf("%s: %w: track limit of %d reached", op, ErrQuotaExceeded, limits.MaxTracks)
+	}
+
+	maxSize := limits.MaxFileBytes
+	if limits.MaxBytes > 0 {
+		left := limits.MaxBytes - usage.Bytes
+		if left <= 0 {
+			return 0, fmt.Errorf("%s: %w: storage limit reached", op, ErrQuotaExceeded)
+		}
+		if maxSize == 0 || left < maxSize {
+			maxSize = left
+		}
+	}
+
+	if size > 0 {
+		if limits.MaxFileBytes > 0 && size > limits.MaxFileBytes {
+			return 0, fmt.Errorf("%s: %w: limit is %d bytes", op, ErrFileTooLarge, limits.MaxFileBytes)
+		}
+		if maxSize > 0 && size > maxSize {
+			return 0, fmt.Errorf("%s: %w: %d bytes left", op, ErrQuotaExceeded, maxSize)
+		}
+	}
+
+	return maxSize, nil
+}
+
+// CheckStored is called once the track and its size are saved. Unlike CheckUpload it counts the track
+// itself, so concurrent uploads that passed the first check can't overrun the quota together.
+func (s *QuotaService) CheckStored(ctx context.Context, user models.User) error {
+	const op = "quota.CheckStored"
+
+	_, limits, err := s.Limits(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	usage, err := s.quotaStore.StorageUsage(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get usage: %w", op, err)
+	}
+
+	if limits.MaxTracks > 0 && usage.Tracks > limits.MaxTracks {
+		return fmt.Errorf("%s: %w: track limit of %d reached", op, ErrQuotaExceeded, limits.MaxTracks)
+	}
+	if limits.MaxBytes > 0 && usage.Bytes > limits.MaxBytes {
+		return fmt.Errorf("%s: %w: storage limit reached", op, ErrQuotaExceeded)
+	}
+
+	return nil
+}
+
+// CheckDuration is called by the worker once the duration of the track is known.
+func (s *QuotaService) CheckDuration(ctx context.Context, trackID int64, duration time.Duration) error {
+	const op = "quota.CheckDuration"
+
+	track, err := s.trackProvider.GetTrack(ctx, trackID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	if track.UploadedBy == nil {
+		return nil
+	}
+
+	_, limits, err := s.Limits(ctx, *track.UploadedBy)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if limits.MaxDuration > 0 && duration > limits.MaxDuration {
+		return fmt.Errorf("%s: %w: limit is %s", op, ErrTooLong, limits.MaxDuration)
+	}
+
+	return nil
+}
+
+// CheckLossless reports whether the plan of the user includes the lossless renditions.
+func (s *QuotaService) CheckLossless(ctx context.Context, user models.User) error {
+	const op = "quota.CheckLossless"
+
+	_, limits, err := s.Limits(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !limits.Lossless {
+		return fmt.Errorf("%s: %w", op, ErrNotEntitled)
+	}
+
+	return nil
+}
+
+// CheckDownload reports whether the plan of the user includes downloads in format.
+func (s *QuotaService) CheckDownload(ctx context.Context, user models.User, format string) error {
+	const op = "quota.CheckDownload"
+
+	_, limits, err := s.Limits(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !slices.Contains(limits.Downloads, format) {
+		return fmt.Errorf("%s: %w", op, ErrNotEntitled)
+	}
+
+	return nil
+}
+
+// SetQuota changes the plan of the user and replaces the per-user overrides.
+func (s *QuotaService) SetQuota(ctx context.Context, actor models.User, userID int64, plan string, override models.QuotaOverride) error {
+	const op = "quota.SetQuota"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.ID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := authz.CanAdminister(actor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, ok := s.plans[plan]; !ok {
+		return fmt.Errorf("%s: %w: %s", op, ErrUnknownPlan, plan)
+	}
+
+	err := s.quotaStore.SetUserPlan(ctx, userID, plan)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to set plan: %w", op, err)
+	}
+
+	if err := s.quotaStore.SetUserQuotaOverride(ctx, userID, override); err != nil {
+		return fmt.Errorf("%s: failed to set quota override: %w", op, err)
+	}
+
+	log.Info("user quota changed", slog.String("plan", plan))
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// fakeStore keeps the plan, overrides and usage of the users in maps, users without a plan don't exist.
+type fakeStore struct {
+	plans     map[int64]string
+	overrides map[int64]models.QuotaOverride
+	usage     map[int64]models.StorageUsage
+	tracks    map[int64]models.Track
+}
+
+func (f *fakeStore) UserPlan(_ context.Context, userID int64) (string, error) {
+	plan, ok := f.plans[userID]
+	if !ok {
+		return "", storage.ErrNotFound
+	}
+	return plan, nil
+}
+
+func (f *fakeStore) SetUserPlan(_ context.Context, userID int64, plan string) error {
+	f.plans[userID] = plan
+	return nil
+}
+
+func (f *fakeStore) UserQuotaOverride(_ context.Context, userID int64) (models.QuotaOverride, error) {
+	return f.overrides[userID], nil
+}
+
+func (f *fakeStore) SetUserQuotaOverride(_ context.Context, userID int64, override models.QuotaOverride) error {
+	f.overrides[userID] = override
+	return nil
+}
+
+func (f *fakeStore) StorageUsage(_ context.Context, userID int64) (models.StorageUsage, error) {
+	return f.usage[userID], nil
+}
+
+func (f *fakeStore) GetTrack(_ context.Context, id int64) (models.Track, error) {
+	track, ok := f.tracks[id]
+	if !ok {
+		return models.Track{}, storage.ErrNotFound
+	}
+	return track, nil
+}
+
+const (
+	free     = int64(1)
+	pro      = int64(2)
+	extended = int64(3)
+	legacy   = int64(4)
+)
+
+func newService() *QuotaService {
+	longer := 20 * time.Minute
+	moreTracks := 10
+
+	uploader := func(id int64) *int64 { return &id }
+
+	store := &fakeStore{
+		plans: map[int64]string{free: "free", pro: "pro", extended: "free", legacy: "retired"},
+		overrides: map[int64]models.QuotaOverride{
+			extended: {MaxDuration: &longer, MaxTracks: &moreTracks},
+		},
+		usage: map[int64]models.StorageUsage{
+			free: {Bytes: 900, Tracks: 2},
+			pro:  {Bytes: 5000, Tracks: 40},
+		},
+		tracks: map[int64]models.Track{
+			10: {ID: 10, UploadedBy: uploader(free)},
+			11: {ID: 11, UploadedBy: uploader(pro)},
+			12: {ID: 12, UploadedBy: uploader(extended)},
+			13: {ID: 13, UploadedBy: uploader(legacy)},
+			14: {ID: 14},
+			15: {ID: 15, UploadedBy: uploader(99)},
+		},
+	}
+
+	plans := map[string]models.Quota{
+		"free": {MaxBytes: 1000, MaxTracks: 3, MaxFileBytes: 300, MaxDuration: 10 * time.Minute, Downloads: []string{"mp3"}},
+		"pro":  {Lossless: true, Downloads: []string{"mp3", "flac"}},
+	}
+
+	return New(slog.New(slog.DiscardHandler), store, store, plans, "free")
+}
+
+func TestCheckDuration(t *testing.T) {
+	s := newService()
+
+	tests := []struct {
+		name     string
+		trackID  int64
+		duration time.Duration
+		wantErr  error
+	}{
+		{name: "within limit", trackID: 10, duration: 9 * time.Minute},
+		{name: "at limit", trackID: 10, duration: 10 * time.Minute},
+		{name: "too long", trackID: 10, duration: 10*time.Minute + time.Second, wantErr: ErrTooLong},
+		{name: "unlimited plan", trackID: 11, duration: 3 * time.Hour},
+		{name: "override raises limit", trackID: 12, duration: 15 * time.Minute},
+		{name: "override still limits", trackID: 12, duration: 25 * time.Minute, wantErr: ErrTooLong},
+		{name: "unknown plan uses default", trackID: 13, duration: 11 * time.Minute, wantErr: ErrTooLong},
+		{name: "no uploader", trackID: 14, duration: 3 * time.Hour},
+		{name: "uploader deleted", trackID: 15, duration: 3 * time.Hour},
+		{name: "missing track", trackID: 16, duration: time.Minute, wantErr: storage.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckDuration(t.Context(), tt.trackID, tt.duration)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CheckDuration() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckDuration() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	s := newService()
+
+	tests := []struct {
+		name    string
+		userID  int64
+		size    int64
+		want    int64
+		wantErr error
+	}{
+		{name: "size unknown", userID: free, want: 100},
+		{name: "fits", userID: free, size: 100, want: 100},
+		{name: "over storage left", userID: free, size: 200, wantErr: ErrQuotaExceeded},
+		{name: "over file limit", userID: free, size: 400, wantErr: ErrFileTooLarge},
+		{name: "unlimited plan", userID: pro, size: 1 << 30, want: 0},
+		{name: "empty account on default plan", userID: legacy, size: 300, want: 300},
+		{name: "unknown user", userID: 99, wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.CheckUpload(t.Context(), models.User{ID: tt.userID}, tt.size)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CheckUpload() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckUpload() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("CheckUpload() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUploadLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   models.StorageUsage
+		wantErr error
+	}{
+		{name: "track limit reached", usage: models.StorageUsage{Bytes: 10, Tracks: 3}, wantErr: ErrQuotaExceeded},
+		{name: "storage full", usage: models.StorageUsage{Bytes: 1000, Tracks: 1}, wantErr: ErrQuotaExceeded},
+		{name: "room left", usage: models.StorageUsage{Bytes: 10, Tracks: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService()
+			s.quotaStore.(*fakeStore).usage[free] = tt.usage
+
+			_, err := s.CheckUpload(t.Context(), models.User{ID: free}, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckUpload() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckStored(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   models.StorageUsage
+		wantErr error
+	}{
+		{name: "last track fits", usage: models.StorageUsage{Bytes: 1000, Tracks: 3}},
+		{name: "too many tracks", usage: models.StorageUsage{Bytes: 10, Tracks: 4}, wantErr: ErrQuotaExceeded},
+		{name: "too many bytes", usage: models.StorageUsage{Bytes: 1001, Tracks: 1}, wantErr: ErrQuotaExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService()
+			s.quotaStore.(*fakeStore).usage[free] = tt.usage
+
+			err := s.CheckStored(t.Context(), models.User{ID: free})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckStored() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s := newService()
+
+	plan, limits, err := s.Limits(t.Context(), extended)
+	if err != nil {
+		t.Fatalf("Limits: %v", err)
+	}
+	if plan != "free" {
+		t.Fatalf("Limits() plan = %q, want free", plan)
+	}
+	if limits.MaxDuration != 20*time.Minute || limits.MaxTracks != 10 || limits.MaxBytes != 1000 || limits.MaxFileBytes != 300 {
+		t.Fatalf("Limits() = %+v, want the free plan with the overrides", limits)
+	}
+}
+
+func TestEntitlements(t *testing.T) {
+	s := newService()
+
+	tests := []struct {
+		name    string
+		check   func() error
+		wantErr error
+	}{
+		{name: "lossless on pro", check: func() error { return s.CheckLossless(t.Context(), models.User{ID: pro}) }},
+		{name: "lossless on free", check: func() error { return s.CheckLossless(t.Context(), models.User{ID: free}) }, wantErr: ErrNotEntitled},
+		{name: "flac on pro", check: func() error { return s.CheckDownload(t.Context(), models.User{ID: pro}, "flac") }},
+		{name: "flac on free", check: func() error { return s.CheckDownload(t.Context(), models.User{ID: free}, "flac") }, wantErr: ErrNotEntitled},
+		{name: "mp3 on free", check: func() error { return s.CheckDownload(t.Context(), models.User{ID: free}, "mp3") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package cover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// MaxSourceSize bounds an uploaded or embedded cover before it is decoded.
+const MaxSourceSize = 10 << 20
+
+var (
+	ErrTrackNotFound = errors.New("track not found")
+	ErrCoverNotFound = errors.New("cover not found")
+	ErrFormat        = errors.New("unsupported format")
+	ErrAccessDenied  = errors.New("access denied")
+)
+
+// Request describes a cover request. Size is rounded up to the closest stored variant,
+// User is nil for anonymous listeners.
+type Request struct {
+	TrackID    int64
+	Size       int
+	Format     string
+	ShareToken string
+	User       *models.User
+}
+
+type CoverService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	mediaProvider MediaProvider
+	shareVerifier ShareVerifier
+
+	bucket  string
+	profile Profile
+}
+
+// Profile describes the stored variants. Sizes are the sides of the square images in pixels,
+// Quality is the JPEG quality, WebP variants are lossless.
+type Profile struct {
+	Sizes   []int
+	Quality int
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+	GetOriginKey(ctx context.Context, id int64) (string, string, error)
+	SetTrackCover(ctx context.Context, id int64, hasCover bool) error
+}
+
+type MediaProvider interface {
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
+	PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error
+}
+
+type ShareVerifier interface {
+	ValidateShare(ctx context.Context, raw string, trackID int64, consume bool) error
+}
+
+// New creates a cover service. The worker generates covers and passes a nil shareVerifier,
+// the service serves them and needs it to let share link holders see unlisted tracks.
+func New(log *slog.Logger, trackProvider TrackProvider, mediaProvider MediaProvider, shareVerifier ShareVerifier, bucket string, profile Profile) *CoverService {
+	return &CoverService{
+		log:           log,
+		trackProvider: trackProvider,
+		mediaProvider: mediaProvider,
+		shareVerifier: shareVerifier,
+		bucket:        bucket,
+		profile:       profile,
+	}
+}
+
+// Generate stores the variants of the cover uploaded with the track or, without one,
+// of the picture embedded in the original file at originalPath.
+func (s *CoverService) Generate(ctx context.Context, id int64, originalPath string) error {
+	const op = "cover.Generate"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+	)
+
+	source, err := s.source(ctx, id, originalPath)
+	if errors.Is(err, media.ErrNoCover) {
+		log.Info("track has no cover")
+
+		if err := s.trackProvider.SetTrackCover(ctx, id, false); err != nil {
+			return fmt.Errorf("%s: failed to save cover: %w", op, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	img, err := media.DecodeCover(source)
+	if err != nil {
+		return fmt.Errorf("%s: failed to decode cover: %w", op, err)
+	}
+
+	log.Info("resizing cover", slog.Any("sizes", s.profile.Sizes))
+
+	for _, size := range s.profile.Sizes {
+		variant := media.ResizeCover(img, size)
+
+		for _, format := range []string{media.CoverJPEG, media.CoverWebP} {
+			data, err := media.EncodeCover(variant, format, s.profile.Quality)
+			if err != nil {
+				return fmt.Errorf("%s: failed to encode cover: %w", op, err)
+			}
+
+			key := media.GenerateTrackCoverKey(id, size, format)
+			if err := s.mediaProvider.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), media.DetectContentType(key)); err != nil {
+				return fmt.Errorf("%s: failed to upload cover: %w", op, err)
+			}
+		}
+	}
+
+	if err := s.trackProvider.SetTrackCover(ctx, id, true); err != nil {
+		return fmt.Errorf("%s: failed to save cover: %w", op, err)
+	}
+
+	log.Info("cover stored")
+
+	return nil
+}
+
+// source returns the uploaded cover, falling back to the embedded one.
+func (s *CoverService) source(ctx context.Context, id int64, originalPath string) ([]byte, error) {
+	bucket, _, err := s.trackProvider.GetOriginKey(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get origin key: %w", err)
+	}
+
+	body, _, err := s.mediaProvider.GetObject(ctx, bucket, media.GenerateTrackCoverSourceKey(id), nil)
+	if errors.Is(err, storage.ErrNotFound) {
+		data, err := media.ExtractCover(ctx, originalPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MaxSourceSize {
+			return nil, fmt.Errorf("embedded cover is too large: %d bytes", len(data))
+		}
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download cover: %w", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, MaxSourceSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download cover: %w", err)
+	}
+	return data, nil
+}
+
+// GetCover returns the stored variant of a track the requester may view.
+func (s *CoverService) GetCover(ctx context.Context, req Request) (io.ReadCloser, storage.ObjectInfo, error) {
+	const op = "cover.GetCover"
+
+	if req.Format == "" {
+		req.Format = media.CoverJPEG
+	}
+	if req.Format != media.CoverJPEG && req.Format != media.CoverWebP {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrFormat)
+	}
+
+	size, ok := s.variant(req.Size)
+	if !ok {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrCoverNotFound)
+	}
+
+	track, err := s.trackProvider.GetTrack(ctx, req.TrackID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrTrackNotFound)
+	}
+	if err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	if err := s.checkAccess(ctx, req, track); err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+	}
+
+	if !track.HasCover {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrCoverNotFound)
+	}
+
+	key := media.GenerateTrackCoverKey(track.ID, size, req.Format)
+	rc, info, err := s.mediaProvider.GetObject(ctx, s.bucket, key, nil)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrCoverNotFound)
+	}
+	if err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to get cover: %w", op, err)
+	}
+
+	return rc, info, nil
+}
+
+// variant returns the smallest stored size that is at least size, or the largest one.
+func (s *CoverService) variant(size int) (int, bool) {
+	if len(s.profile.Sizes) == 0 {
+		return 0, false
+	}
+
+	sizes := slices.Sorted(slices.Values(s.profile.Sizes))
+	for _, v := range sizes {
+		if v >= size {
+			return v, true
+		}
+	}
+	return sizes[len(sizes)-1], true
+}
+
+// checkAccess follows the stream rules but never counts a share link play.
+func (s *CoverService) checkAccess(ctx context.Context, req Request, track models.Track) error {
+	var user models.User
+	if req.User != nil {
+		user = *req.User
+	}
+
+	if err := authz.CanViewTrack(user, track); err == nil {
+		return nil
+	}
+
+	if track.Hidden || track.Visibility == models.VisibilityPrivate || req.ShareToken == "" || s.shareVerifier == nil {
+		return authz.ErrForbidden
+	}
+
+	return s.shareVerifier.ValidateShare(ctx, req.ShareToken, track.ID, false)
+}
@@ -0,0 +1,299 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+var (
+	ErrTrackNotFound = errors.New("track not found")
+	ErrTrackNotReady = errors.New("track is not ready")
+	ErrFormat        = errors.New("unsupported format")
+	ErrPending       = errors.New("download is being prepared")
+	ErrAccessDenied  = errors.New("access denied")
+)
+
+// Request describes a download request of a logged-in user, Format defaults to the original file.
+type Request struct {
+	TrackID int64
+	Format  string
+	User    models.User
+}
+
+// File is a download ready to be sent. Name is the file name offered to the client.
+type File struct {
+	Body io.ReadCloser
+	Info storage.ObjectInfo
+	Name string
+}
+
+type DownloadService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	mediaProvider MediaProvider
+	taskProducer  TaskProducer
+	entitlements  EntitlementChecker
+
+	bucket  string
+	profile Profile
+}
+
+// Profile describes the transcoded files. CoverSize is the stored cover variant written as the front cover,
+// zero leaves the files without one.
+type Profile struct {
+	CoverSize int
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+}
+
+type MediaProvider interface {
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
+	PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error
+}
+
+type TaskProducer interface {
+	SendDownloadTask(ctx context.Context, trackId string, format string) error
+}
+
+// EntitlementChecker decides in which formats listeners may download tracks they don't own.
+type EntitlementChecker interface {
+	CheckDownload(ctx context.Context, user models.User, format string) error
+}
+
+// New creates a download service. The worker transcodes and passes a nil taskProducer and entitlements.
+// In the service a nil entitlements leaves downloads to the owners of the tracks and admins.
+func New(
+	log *slog.Logger,
+	trackProvider TrackProvider,
+	mediaProvider MediaProvider,
+	taskProducer TaskProducer,
+	entitlements EntitlementChecker,
+	bucket string,
+	profile Profile,
+) *DownloadService {
+	return &DownloadService{
+		log:           log,
+		trackProvider: trackProvider,
+		mediaProvider: mediaProvider,
+		taskProducer:  taskProducer,
+		entitlements:  entitlements,
+		bucket:        bucket,
+		profile:       profile,
+	}
+}
+
+// GetDownload returns the original file or the cached transcode of the track. A missing transcode is
+// queued for the worker and ErrPending is returned, the client retries once it is stored.
+func (s *DownloadService) GetDownload(ctx context.Context, req Request) (File, error) {
+	const op = "download.GetDownload"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", req.TrackID),
+		slog.Int64("user_id", req.User.ID),
+		slog.String("format", req.Format),
+	)
+
+	if req.Format == "" {
+		req.Format = media.DownloadOriginal
+	}
+	if req.Format != media.DownloadOriginal && !slices.Contains(media.TranscodeFormats, req.Format) {
+		return File{}, fmt.Errorf("%s: %w", op, ErrFormat)
+	}
+
+	track, err := s.trackProvider.GetTrack(ctx, req.TrackID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return File{}, fmt.Errorf("%s: %w", op, ErrTrackNotFound)
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	if err := s.checkAccess(ctx, req, track); err != nil {
+		return File{}, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+	}
+
+	if req.Format == media.DownloadOriginal {
+		rc, info, err := s.mediaProvider.GetObject(ctx, track.OriginBucket, track.OriginKey, nil)
+		if err != nil {
+			return File{}, fmt.Errorf("%s: failed to get original: %w", op, err)
+		}
+
+		log.Info("original downloaded")
+
+		return File{Body: rc, Info: info, Name: fileName(track.Title, filepath.Ext(track.OriginKey))}, nil
+	}
+
+	if track.Status != storage.StatusReady {
+		return File{}, fmt.Errorf("%s: %w", op, ErrTrackNotReady)
+	}
+
+	key := media.GenerateTrackDownloadKey(track.ID, req.Format, s.tagsVersion(track))
+	rc, info, err := s.mediaProvider.GetObject(ctx, s.bucket, key, nil)
+	if errors.Is(err, storage.ErrNotFound) {
+		if err := s.taskProducer.SendDownloadTask(ctx, strconv.FormatInt(track.ID, 10), req.Format); err != nil {
+			return File{}, fmt.Errorf("%s: failed to send task: %w", op, err)
+		}
+
+		log.Info("transcode queued")
+
+		return File{}, fmt.Errorf("%s: %w", op, ErrPending)
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("%s: failed to get download: %w", op, err)
+	}
+
+	log.Info("transcode downloaded")
+
+	return File{Body: rc, Info: info, Name: fileName(track.Title, "."+req.Format)}, nil
+}
+
+// checkAccess lets owners and admins download anything. Other listeners need to be able to view the track
+// and a plan that includes the format, the original file is never theirs to download. Without an entitlement
+// checker there is no plan to check, so they are refused.
+func (s *DownloadService) checkAccess(ctx context.Context, req Request, track models.Track) error {
+	if err := authz.CanDownloadTrack(req.User, track); err == nil {
+		return nil
+	}
+
+	if req.Format == media.DownloadOriginal {
+		return authz.ErrForbidden
+	}
+	if err := authz.CanViewTrack(req.User, track); err != nil {
+		return err
+	}
+	if s.entitlements == nil {
+		return authz.ErrForbidden
+	}
+
+	return s.entitlements.CheckDownload(ctx, req.User, req.Format)
+}
+
+// Transcode stores the track in format with its title, gain and cover as tags. It does nothing
+// when the transcode is already cached, so duplicate tasks are cheap.
+func (s *DownloadService) Transcode(ctx context.Context, id int64, format string) error {
+	const op = "download.Transcode"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.String("format", format),
+	)
+
+	track, err := s.trackProvider.GetTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	key := media.GenerateTrackDownloadKey(id, format, s.tagsVersion(track))
+	if rc, _, err := s.mediaProvider.GetObject(ctx, s.bucket, key, nil); err == nil {
+		rc.Close()
+
+		log.Info("transcode already stored")
+
+		return nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: failed to check cached transcode: %w", op, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("download-%d-*", id))
+	if err != nil {
+		return fmt.Errorf("%s: failed to create temp dir: %w", op, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localOriginal := filepath.Join(tmpDir, "original"+filepath.Ext(track.OriginKey))
+	if err := s.fetch(ctx, track.OriginBucket, track.OriginKey, localOriginal); err != nil {
+		return fmt.Errorf("%s: failed to download original track: %w", op, err)
+	}
+
+	tags := media.Tags{
+		Title:  track.Title,
+		GainDB: track.GainDB,
+	}
+	if s.hasCover(track) {
+		tags.CoverPath = filepath.Join(tmpDir, "cover."+media.CoverJPEG)
+		if err := s.fetch(ctx, s.bucket, media.GenerateTrackCoverKey(id, s.profile.CoverSize, media.CoverJPEG), tags.CoverPath); err != nil {
+			return fmt.Errorf("%s: failed to download cover: %w", op, err)
+		}
+	}
+
+	log.Info("transcoding")
+
+	localOut := filepath.Join(tmpDir, "download."+format)
+	if err := media.Transcode(ctx, localOriginal, localOut, format, tags); err != nil {
+		return fmt.Errorf("%s: failed to transcode: %w", op, err)
+	}
+
+	file, size, err := media.OpenFile(localOut)
+	if err != nil {
+		return fmt.Errorf("%s: failed to open transcode: %w", op, err)
+	}
+	defer file.Close()
+
+	if err := s.mediaProvider.PutObject(ctx, s.bucket, key, file, size, media.DetectContentType(localOut)); err != nil {
+		return fmt.Errorf("%s: failed to upload transcode: %w", op, err)
+	}
+
+	log.Info("transcode stored", slog.String("key", key))
+
+	return nil
+}
+
+func (s *DownloadService) fetch(ctx context.Context, bucket, key, path string) error {
+	body, _, err := s.mediaProvider.GetObject(ctx, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return media.WriteToFile(path, body)
+}
+
+func (s *DownloadService) hasCover(track models.Track) bool {
+	return track.HasCover && s.profile.CoverSize > 0
+}
+
+// tagsVersion identifies the tags a transcode is written with.
+func (s *DownloadService) tagsVersion(track models.Track) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%t", track.Title, s.hasCover(track))
+	if track.GainDB != nil {
+		fmt.Fprintf(h, "\x00%.2f", *track.GainDB)
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// fileName builds the name of the downloaded file from the track title.
+func fileName(title, ext string) string {
+	name := make([]rune, 0, len(title))
+	for _, r := range title {
+		switch {
+		case r < 0x20, r == 0x7f:
+		case r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|':
+			name = append(name, '_')
+		default:
+			name = append(name, r)
+		}
+	}
+	if len(name) == 0 {
+		return "track" + ext
+	}
+	return string(name) + ext
+}
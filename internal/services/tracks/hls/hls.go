@@ -5,45 +5,139 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
 	"github.com/Sheridanlk/Music-Service/internal/lib/media"
 	"github.com/Sheridanlk/Music-Service/internal/storage"
 )
 
+const keyURLPattern = "/keys/%d/%s/%d"
+
 type HlsSegmenter struct {
 	log *slog.Logger
 
 	trackProvider TrackProvider
 	mediaProvider MediaProvider
+	keySaver      KeySaver
+	keyWrapper    KeyWrapper
+	quotaChecker  QuotaChecker
+	taskProducer  TaskProducer
+	covers        CoverGenerator
 
 	hlsBucket string
+	profile   Profile
+}
+
+// Profile describes how tracks are segmented.
+// When Encrypt is set, segments are encrypted with AES-128 and a new key is used every KeyRotation segments.
+// With FMP4 the AAC rendition is written as CMAF fragments instead of packed audio, DASH adds an MPD
+// manifest for the same fragments.
+// Waveform and Preview queue those tasks once the track is ready.
+type Profile struct {
+	SegmentSeconds int
+	FMP4           bool
+	DASH           bool
+	Encrypt        bool
+	KeyRotation    int
+	Loudness       LoudnessProfile
+	Lossless       LosslessProfile
+	Waveform       bool
+	Preview        bool
+}
+
+// LosslessProfile adds a FLAC or ALAC rendition in fMP4 for lossless sources. It is never normalized,
+// players apply the stored gain themselves.
+type LosslessProfile struct {
+	Enabled bool
+	Codec   string
+}
+
+// LoudnessProfile enables the EBU R128 measurement of every track. With Normalize the rendition
+// is normalized to Target, otherwise clients get the gain to apply themselves.
+type LoudnessProfile struct {
+	Measure   bool
+	Normalize bool
+	Target    media.LoudnessTarget
 }
 
 type TrackProvider interface {
-	GetOriginKey(ctx context.Context, id int64) (string, string, error)
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
 	SetHLS(ctx context.Context, id int64, hlsBucket string, hlsPrefix string) error
+	SetTrackDuration(ctx context.Context, id int64, duration time.Duration) error
+	SetTrackLoudness(ctx context.Context, id int64, loudness models.Loudness) error
+	SetLossless(ctx context.Context, id int64, losslessPrefix *string) error
+	SetDASH(ctx context.Context, id int64, hasDASH bool) error
 	SetStatusProcessing(ctx context.Context, id int64) error
 	SetStatusReady(ctx context.Context, id int64) error
 	SetStatusError(ctx context.Context, id int64) error
 }
 
 type MediaProvider interface {
-	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, string, int64, error)
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
 	PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error
+	ListObjects(ctx context.Context, bucketName, prefix string) ([]storage.ObjectInfo, error)
+	RemoveObjects(ctx context.Context, bucketName string, objectNames []string) error
+}
+
+type KeySaver interface {
+	SaveTrackKeys(ctx context.Context, trackID int64, run string, keys []models.TrackKey) error
+	DeleteStaleTrackKeys(ctx context.Context, trackID int64, keepRun string) error
 }
 
-func New(log *slog.Logger, trackProvider TrackProvider, mediaProvider MediaProvider, hlsBucket string) *HlsSegmenter {
+type KeyWrapper interface {
+	Wrap(key []byte) ([]byte, error)
+}
+
+type QuotaChecker interface {
+	CheckDuration(ctx context.Context, trackID int64, duration time.Duration) error
+}
+
+// TaskProducer queues the tasks that derive more data from a processed track.
+type TaskProducer interface {
+	SendWaveformTask(ctx context.Context, trackId string) error
+	SendPreviewTask(ctx context.Context, trackId string) error
+}
+
+// CoverGenerator stores the cover variants of a track from its uploaded or embedded art.
+type CoverGenerator interface {
+	Generate(ctx context.Context, id int64, originalPath string) error
+}
+
+// New creates a segmenter. keyWrapper is only used when the profile enables encryption,
+// the track duration is not limited when quotaChecker is nil, no follow-up tasks are queued when taskProducer is nil
+// and covers are skipped when covers is nil.
+func New(
+	log *slog.Logger,
+	trackProvider TrackProvider,
+	mediaProvider MediaProvider,
+	keySaver KeySaver,
+	keyWrapper KeyWrapper,
+	quotaChecker QuotaChecker,
+	taskProducer TaskProducer,
+	covers CoverGenerator,
+	hlsBucket string,
+	profile Profile,
+) *HlsSegmenter {
 	return &HlsSegmenter{
 		log:           log,
 		trackProvider: trackProvider,
 		mediaProvider: mediaProvider,
+		keySaver:      keySaver,
+		keyWrapper:    keyWrapper,
+		quotaChecker:  quotaChecker,
+		taskProducer:  taskProducer,
+		covers:        covers,
 		hlsBucket:     hlsBucket,
+		profile:       profile,
 	}
 }
 
 // Hls processes the uploaded track, converts it to HLS format, and uploads HLS files to storage.
+// Every run stores its renditions under a new prefix and removes the ones of the previous run once the track points to the new ones.
 func (s *HlsSegmenter) Hls(ctx context.Context, id int64) (err error) {
 	const op = "tracks.Hls"
 
@@ -52,10 +146,11 @@ func (s *HlsSegmenter) Hls(ctx context.Context, id int64) (err error) {
 		slog.String("track_id", fmt.Sprintf("%d", id)),
 	)
 
-	bucket, originKey, err := s.trackProvider.GetOriginKey(ctx, id)
+	previous, err := s.trackProvider.GetTrack(ctx, id)
 	if err != nil {
-		return fmt.Errorf("%s: failed to get origin key: %w", op, err)
+		return fmt.Errorf("%s: failed to get track: %w", op, err)
 	}
+	bucket, originKey := previous.OriginBucket, previous.OriginKey
 
 	if err := s.trackProvider.SetStatusProcessing(ctx, id); err != nil {
 		return fmt.Errorf("%s: failed to set status processing: %w", op, err)
@@ -79,7 +174,7 @@ func (s *HlsSegmenter) Hls(ctx context.Context, id int64) (err error) {
 	os.Mkdir(hlsLocalDir, 0755)
 
 	log.Info("downloading original track", slog.String("bucket", bucket), slog.String("key", originKey))
-	body, _, _, err := s.mediaProvider.GetObject(ctx, bucket, originKey, nil)
+	body, _, err := s.mediaProvider.GetObject(ctx, bucket, originKey, nil)
 	if err != nil {
 		return fmt.Errorf("%s: failed to download original track: %w", op, err)
 	}
@@ -89,19 +184,82 @@ func (s *HlsSegmenter) Hls(ctx context.Context, id int64) (err error) {
 		return fmt.Errorf("%s: failed to save original track to local file: %w", op, err)
 	}
 
-	log.Info("starting segmentation")
+	duration, err := media.ProbeDuration(ctx, localOriginal)
+	if err != nil {
+		return fmt.Errorf("%s: failed to probe duration: %w", op, err)
+	}
+
+	if err := s.trackProvider.SetTrackDuration(ctx, id, duration); err != nil {
+		return fmt.Errorf("%s: failed to save duration: %w", op, err)
+	}
+
+	if s.quotaChecker != nil {
+		if err := s.quotaChecker.CheckDuration(ctx, id, duration); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	var audioFilter string
+	if s.profile.Loudness.Measure {
+		audioFilter, err = s.loudness(ctx, id, localOriginal)
+		if err != nil {
+			return fmt.Errorf("%s: failed to measure loudness: %w", op, err)
+		}
+	}
+
+	log.Info("starting segmentation", slog.String("duration", duration.String()))
 
-	if err := media.ToHLS(ctx, localOriginal, hlsLocalDir, 4); err != nil {
+	opts := media.HLSOptions{
+		SegmentSeconds: s.profile.SegmentSeconds,
+		Codec:          media.CodecAAC,
+		FMP4:           s.profile.FMP4,
+		DASH:           s.profile.DASH,
+		AudioFilter:    audioFilter,
+	}
+	if err := media.ToHLS(ctx, localOriginal, hlsLocalDir, opts); err != nil {
 		return fmt.Errorf("%s: failed to convert to hls: %w", op, err)
 	}
 
-	hlsPrefix := media.GenerateTrackHLSKey(id)
+	run := media.NewRun()
+
+	if s.profile.Encrypt {
+		log.Info("encrypting segments", slog.Int("key_rotation", s.profile.KeyRotation))
+
+		if err := s.encrypt(ctx, id, run, hlsLocalDir); err != nil {
+			return fmt.Errorf("%s: failed to encrypt hls: %w", op, err)
+		}
+	}
+
+	hlsPrefix := media.GenerateTrackHLSKey(id, run)
 	log.Info("uploading generated hls files", slog.String("prefix", hlsPrefix))
 
-	if err := uploadFolder(ctx, s.mediaProvider, s.hlsBucket, hlsLocalDir, hlsPrefix); err != nil {
+	if err := media.UploadDir(ctx, s.mediaProvider, s.hlsBucket, hlsLocalDir, hlsPrefix); err != nil {
 		return fmt.Errorf("%s: failed to upload hls files: %w", op, err)
 	}
 
+	// The lossy rendition is enough to play the track, so a failed lossless one is only logged.
+	var losslessPrefix *string
+	if s.profile.Lossless.Enabled {
+		losslessPrefix, err = s.lossless(ctx, id, run, localOriginal, tmpDir)
+		if err != nil {
+			log.Error("failed to create lossless rendition", slog.String("error", err.Error()))
+		}
+		if err := s.trackProvider.SetLossless(ctx, id, losslessPrefix); err != nil {
+			return fmt.Errorf("%s: failed to save lossless info: %w", op, err)
+		}
+	}
+
+	// A broken picture shouldn't keep the audio from being played.
+	if s.covers != nil {
+		if err := s.covers.Generate(ctx, id, localOriginal); err != nil {
+			log.Error("failed to generate cover", slog.String("error", err.Error()))
+		}
+	}
+
+	if err := s.trackProvider.SetDASH(ctx, id, s.profile.DASH); err != nil {
+		return fmt.Errorf("%s: failed to save dash info: %w", op, err)
+	}
+
 	if err := s.trackProvider.SetHLS(ctx, id, s.hlsBucket, hlsPrefix); err != nil {
 		return fmt.Errorf("%s: failed to save hls info: %w", op, err)
 	}
@@ -112,36 +270,138 @@ func (s *HlsSegmenter) Hls(ctx context.Context, id int64) (err error) {
 
 	log.Info("hls processing completed successfully")
 
+	// Nothing points to the renditions of the previous run anymore, a failed removal only wastes space.
+	for _, prefix := range []*string{previous.HLSPrefix, previous.LosslessPrefix} {
+		if prefix == nil || previous.HLSBucket == nil {
+			continue
+		}
+		if err := media.RemovePrefix(ctx, s.mediaProvider, *previous.HLSBucket, *prefix); err != nil {
+			log.Error("failed to remove previous rendition", slog.String("prefix", *prefix), slog.String("error", err.Error()))
+		}
+	}
+	if err := s.keySaver.DeleteStaleTrackKeys(ctx, id, run); err != nil {
+		log.Error("failed to remove previous keys", slog.String("error", err.Error()))
+	}
+
+	// The track is already playable, a lost task can be sent again with musicctl.
+	if s.taskProducer != nil && s.profile.Waveform {
+		if err := s.taskProducer.SendWaveformTask(ctx, fmt.Sprintf("%d", id)); err != nil {
+			log.Error("failed to send waveform task", slog.String("error", err.Error()))
+		}
+	}
+	if s.taskProducer != nil && s.profile.Preview {
+		if err := s.taskProducer.SendPreviewTask(ctx, fmt.Sprintf("%d", id)); err != nil {
+			log.Error("failed to send preview task", slog.String("error", err.Error()))
+		}
+	}
+
 	return nil
 }
 
-func uploadFolder(ctx context.Context, mediaProvider MediaProvider, hlsBucket, dirPath, prefix string) error {
-	entries, err := os.ReadDir(dirPath)
+// loudness measures the original, stores the result and returns the normalization filter
+// for the rendition, which is empty unless the profile normalizes.
+// Silence has no integrated loudness and is neither stored nor normalized.
+func (s *HlsSegmenter) loudness(ctx context.Context, id int64, path string) (string, error) {
+	target := s.profile.Loudness.Target
+
+	m, err := media.MeasureLoudness(ctx, path, target)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
+	if math.IsInf(m.Integrated, 0) || math.IsInf(m.TruePeak, 0) {
+		s.log.Warn("track is silent, skipping loudness", slog.Int64("track_id", id))
+		return "", nil
+	}
 
-		localPath := filepath.Join(dirPath, entry.Name())
-		objectKey := prefix + entry.Name()
+	loudness := models.Loudness{
+		Integrated: m.Integrated,
+		TruePeak:   m.TruePeak,
+		Range:      m.Range,
+		Gain:       math.Round((target.Integrated-m.Integrated)*100) / 100,
+	}
+	if s.profile.Loudness.Normalize {
+		loudness.Gain = 0
+	}
 
-		file, size, err := media.OpenFile(localPath)
-		if err != nil {
-			return err
-		}
+	if err := s.trackProvider.SetTrackLoudness(ctx, id, loudness); err != nil {
+		return "", err
+	}
 
-		ct := media.DetectContentType(entry.Name())
+	s.log.Info("loudness measured",
+		slog.Int64("track_id", id),
+		slog.Float64("integrated", m.Integrated),
+		slog.Float64("true_peak", m.TruePeak),
+		slog.Float64("range", m.Range),
+	)
 
-		err = mediaProvider.PutObject(ctx, hlsBucket, objectKey, file, size, ct)
-		file.Close()
+	if !s.profile.Loudness.Normalize {
+		return "", nil
+	}
+	return media.LoudnormFilter(target, m), nil
+}
 
+// lossless encodes and uploads the lossless rendition and returns its prefix,
+// which is nil when the source is lossy.
+func (s *HlsSegmenter) lossless(ctx context.Context, id int64, run, path, tmpDir string) (*string, error) {
+	codec, err := media.ProbeAudioCodec(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !media.IsLosslessCodec(codec) {
+		return nil, nil
+	}
+
+	dir := filepath.Join(tmpDir, "lossless")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("creating lossless rendition", slog.Int64("track_id", id), slog.String("source_codec", codec))
+
+	opts := media.HLSOptions{
+		SegmentSeconds: s.profile.SegmentSeconds,
+		Codec:          s.profile.Lossless.Codec,
+		FMP4:           true,
+		DASH:           s.profile.DASH,
+	}
+	if err := media.ToHLS(ctx, path, dir, opts); err != nil {
+		return nil, err
+	}
+
+	prefix := media.GenerateTrackLosslessKey(id, run, s.profile.Lossless.Codec)
+	if err := media.UploadDir(ctx, s.mediaProvider, s.hlsBucket, dir, prefix); err != nil {
+		return nil, err
+	}
+
+	return &prefix, nil
+}
+
+// encrypt encrypts the segments in place and stores the wrapped keys before anything is uploaded,
+// so a published playlist never points to a missing key.
+// The keys belong to the run, the published rendition keeps using its own until the track switches to this one.
+func (s *HlsSegmenter) encrypt(ctx context.Context, id int64, run, dir string) error {
+	keys, err := media.EncryptHLS(dir, s.profile.KeyRotation, func(index int) string {
+		return fmt.Sprintf(keyURLPattern, id, run, index)
+	})
+	if err != nil {
+		return err
+	}
+
+	trackKeys := make([]models.TrackKey, len(keys))
+	for i, k := range keys {
+		material, err := s.keyWrapper.Wrap(k.Key)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to wrap key: %w", err)
+		}
+
+		trackKeys[i] = models.TrackKey{
+			TrackID:  id,
+			Run:      run,
+			Index:    k.Index,
+			Material: material,
 		}
 	}
-	return nil
+
+	return s.keySaver.SaveTrackKeys(ctx, id, run, trackKeys)
 }
@@ -0,0 +1,139 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/streamtoken"
+)
+
+var ErrAccessDenied = errors.New("access denied")
+
+// Request describes a key request. User is nil for anonymous listeners, Run is empty
+// for playlists published before keys were kept per processing run.
+type Request struct {
+	TrackID    int64
+	Run        string
+	Index      int
+	Token      string
+	ShareToken string
+	User       *models.User
+}
+
+type KeyService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	keyProvider   KeyProvider
+	keyUnwrapper  KeyUnwrapper
+	tokenVerifier TokenVerifier
+	shareVerifier ShareVerifier
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+}
+
+type KeyProvider interface {
+	GetTrackKey(ctx context.Context, trackID int64, run string, index int) (models.TrackKey, error)
+}
+
+type KeyUnwrapper interface {
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+type TokenVerifier interface {
+	Verify(token string, trackID int64, userID string) (streamtoken.Claims, error)
+}
+
+type ShareVerifier interface {
+	ValidateShare(ctx context.Context, raw string, trackID int64, consume bool) error
+}
+
+// New creates a key delivery service. Stream tokens are not checked when tokenVerifier is nil, otherwise
+// they must have been issued to the listener of the request. The track visibility is always checked.
+func New(
+	log *slog.Logger,
+	trackProvider TrackProvider,
+	keyProvider KeyProvider,
+	keyUnwrapper KeyUnwrapper,
+	tokenVerifier TokenVerifier,
+	shareVerifier ShareVerifier,
+) *KeyService {
+	return &KeyService{
+		log:           log,
+		trackProvider: trackProvider,
+		keyProvider:   keyProvider,
+		keyUnwrapper:  keyUnwrapper,
+		tokenVerifier: tokenVerifier,
+		shareVerifier: shareVerifier,
+	}
+}
+
+// GetKey returns the plain AES-128 key referenced by the #EXT-X-KEY tag of the track playlist
+// to listeners who may view the track or hold a share link to it.
+func (s *KeyService) GetKey(ctx context.Context, req Request) ([]byte, error) {
+	const op = "keys.GetKey"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", req.TrackID),
+		slog.String("run", req.Run),
+		slog.Int("key_index", req.Index),
+	)
+
+	if s.tokenVerifier != nil {
+		var viewerID int64
+		if req.User != nil {
+			viewerID = req.User.ID
+		}
+
+		if _, err := s.tokenVerifier.Verify(req.Token, req.TrackID, streamtoken.Subject(viewerID)); err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+		}
+	}
+
+	track, err := s.trackProvider.GetTrack(ctx, req.TrackID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	if err := s.checkAccess(ctx, req, track); err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+	}
+
+	trackKey, err := s.keyProvider.GetTrackKey(ctx, req.TrackID, req.Run, req.Index)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get key: %w", op, err)
+	}
+
+	key, err := s.keyUnwrapper.Unwrap(trackKey.Material)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to unwrap key: %w", op, err)
+	}
+
+	log.Info("key delivered")
+
+	return key, nil
+}
+
+func (s *KeyService) checkAccess(ctx context.Context, req Request, track models.Track) error {
+	var user models.User
+	if req.User != nil {
+		user = *req.User
+	}
+
+	if err := authz.CanViewTrack(user, track); err == nil {
+		return nil
+	}
+
+	if track.Hidden || track.Visibility == models.VisibilityPrivate || req.ShareToken == "" || s.shareVerifier == nil {
+		return authz.ErrForbidden
+	}
+
+	return s.shareVerifier.ValidateShare(ctx, req.ShareToken, track.ID, false)
+}
@@ -8,8 +8,12 @@ import (
 	"github.com/Sheridanlk/Music-Service/internal/domain/models"
 )
 
+// maxTagFacets bounds the tag facets to the most used tags.
+const maxTagFacets = 20
+
 type TrackLister interface {
-	ListReadyTracks(ctx context.Context, count int, offset int) ([]models.TrackListItem, error)
+	ListReadyTracks(ctx context.Context, filter models.TrackFilter) ([]models.TrackListItem, error)
+	TrackFacets(ctx context.Context, filter models.TrackFilter, maxTags int) (models.TrackFacets, error)
 }
 
 type ListService struct {
@@ -25,7 +29,10 @@ func New(log *slog.Logger, trackLister TrackLister) *ListService {
 	}
 }
 
-func (s *ListService) GetTracksList(ctx context.Context, limit int, offset int) ([]models.TrackListItem, error) {
+// GetTracksList returns a page of public tracks and the viewer's own tracks matching the filter.
+// Anonymous viewers have ViewerID 0. The facets of every matching track are only counted for
+// the first page of a cursor listing, offset listings get them on every page.
+func (s *ListService) GetTracksList(ctx context.Context, filter models.TrackFilter) (models.TrackPage, error) {
 	const op = "list.GetTracksList"
 
 	log := s.log.With(
@@ -34,12 +41,46 @@ func (s *ListService) GetTracksList(ctx context.Context, limit int, offset int)
 
 	log.Info("getting tracks list")
 
-	tracks, err := s.trackLister.ListReadyTracks(ctx, limit, offset)
+	// One more track than asked tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+
+	tracks, err := s.trackLister.ListReadyTracks(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to get tracks list: %w", op, err)
+		return models.TrackPage{}, fmt.Errorf("%s: failed to get tracks list: %w", op, err)
+	}
+
+	page := models.TrackPage{Items: tracks}
+	if len(tracks) > limit {
+		page.Items = tracks[:limit]
+		page.Next = cursorAfter(filter.Sort, page.Items[limit-1])
+	}
+
+	if filter.After == nil {
+		facets, err := s.trackLister.TrackFacets(ctx, filter, maxTagFacets)
+		if err != nil {
+			return models.TrackPage{}, fmt.Errorf("%s: failed to get facets: %w", op, err)
+		}
+		page.Facets = &facets
 	}
 
 	log.Info("tracks geted")
 
-	return tracks, nil
+	return page, nil
+}
+
+// cursorAfter returns the position of the track in the sort order.
+func cursorAfter(sort string, track models.TrackListItem) *models.TrackCursor {
+	cursor := &models.TrackCursor{
+		Sort:      sort,
+		CreatedAt: track.CreatedAt,
+		ID:        track.ID,
+	}
+	switch sort {
+	case models.SortTitle:
+		cursor.Title = track.Title
+	case models.SortPopularity:
+		cursor.Plays = track.Plays
+	}
+	return cursor
 }
@@ -0,0 +1,215 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	lyricslib "github.com/Sheridanlk/Music-Service/internal/lib/lyrics"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// MaxSize bounds the text of uploaded lyrics in bytes.
+const MaxSize = 64 << 10
+
+var (
+	ErrTrackNotFound  = errors.New("track not found")
+	ErrLyricsNotFound = errors.New("lyrics not found")
+	ErrFormat         = errors.New("unsupported lyrics format")
+	ErrInvalidLyrics  = errors.New("invalid lyrics")
+	ErrAccessDenied   = errors.New("access denied")
+)
+
+// Request describes a lyrics request, User is nil for anonymous listeners.
+type Request struct {
+	TrackID    int64
+	ShareToken string
+	User       *models.User
+}
+
+type LyricsService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	lyricsStore   LyricsStore
+	shareVerifier ShareVerifier
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+}
+
+type LyricsStore interface {
+	GetLyrics(ctx context.Context, trackID int64) (models.Lyrics, error)
+	SaveLyrics(ctx context.Context, lyrics models.Lyrics) error
+	DeleteLyrics(ctx context.Context, trackID int64) error
+}
+
+type ShareVerifier interface {
+	ValidateShare(ctx context.Context, raw string, trackID int64, consume bool) error
+}
+
+func New(log *slog.Logger, trackProvider TrackProvider, lyricsStore LyricsStore, shareVerifier ShareVerifier) *LyricsService {
+	return &LyricsService{
+		log:           log,
+		trackProvider: trackProvider,
+		lyricsStore:   lyricsStore,
+		shareVerifier: shareVerifier,
+	}
+}
+
+// GetLyrics returns the stored lyrics of a track the requester may view together with their lines.
+func (s *LyricsService) GetLyrics(ctx context.Context, req Request) (models.Lyrics, []lyricslib.Line, error) {
+	const op = "lyrics.GetLyrics"
+
+	track, err := s.getTrack(ctx, req.TrackID)
+	if err != nil {
+		return models.Lyrics{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.checkAccess(ctx, req, track); err != nil {
+		return models.Lyrics{}, nil, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+	}
+
+	lyrics, err := s.lyricsStore.GetLyrics(ctx, track.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.Lyrics{}, nil, fmt.Errorf("%s: %w", op, ErrLyricsNotFound)
+	}
+	if err != nil {
+		return models.Lyrics{}, nil, fmt.Errorf("%s: failed to get lyrics: %w", op, err)
+	}
+
+	lines, err := Parse(lyrics.Format, lyrics.Text)
+	if err != nil {
+		return models.Lyrics{}, nil, fmt.Errorf("%s: failed to parse stored lyrics: %w", op, err)
+	}
+
+	return lyrics, lines, nil
+}
+
+// SetLyrics validates and replaces the lyrics of the track. Synced lines must not start after the end of the track.
+func (s *LyricsService) SetLyrics(ctx context.Context, user models.User, lyrics models.Lyrics) error {
+	const op = "lyrics.SetLyrics"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", lyrics.TrackID),
+		slog.Int64("user_id", user.ID),
+	)
+
+	track, err := s.getTrack(ctx, lyrics.TrackID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanEditLyrics(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(lyrics.Text) > MaxSize {
+		return fmt.Errorf("%s: %w: longer than %d bytes", op, ErrInvalidLyrics, MaxSize)
+	}
+
+	lines, err := Parse(lyrics.Format, lyrics.Text)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if track.Duration != nil && lyrics.Format == models.LyricsLRC {
+		if last := lines[len(lines)-1]; last.Start > *track.Duration {
+			return fmt.Errorf("%s: %w: line at %s starts after the end of the track", op, ErrInvalidLyrics, last.Start)
+		}
+	}
+
+	if err := s.lyricsStore.SaveLyrics(ctx, lyrics); err != nil {
+		return fmt.Errorf("%s: failed to save lyrics: %w", op, err)
+	}
+
+	log.Info("lyrics saved", slog.String("format", lyrics.Format), slog.Int("lines", len(lines)))
+
+	return nil
+}
+
+func (s *LyricsService) DeleteLyrics(ctx context.Context, user models.User, trackID int64) error {
+	const op = "lyrics.DeleteLyrics"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", trackID),
+		slog.Int64("user_id", user.ID),
+	)
+
+	track, err := s.getTrack(ctx, trackID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanEditLyrics(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.lyricsStore.DeleteLyrics(ctx, trackID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrLyricsNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to delete lyrics: %w", op, err)
+	}
+
+	log.Info("lyrics deleted")
+
+	return nil
+}
+
+// Parse splits lyrics of the format into lines, LRC timestamps are validated.
+func Parse(format, text string) ([]lyricslib.Line, error) {
+	var lines []lyricslib.Line
+	var err error
+
+	switch format {
+	case models.LyricsPlain:
+		lines, err = lyricslib.ParsePlain(text)
+	case models.LyricsLRC:
+		lines, err = lyricslib.ParseLRC(text)
+	default:
+		return nil, ErrFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLyrics, err)
+	}
+
+	return lines, nil
+}
+
+func (s *LyricsService) getTrack(ctx context.Context, id int64) (models.Track, error) {
+	track, err := s.trackProvider.GetTrack(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.Track{}, ErrTrackNotFound
+	}
+	if err != nil {
+		return models.Track{}, fmt.Errorf("failed to get track: %w", err)
+	}
+
+	return track, nil
+}
+
+// checkAccess follows the stream rules but never counts a share link play.
+func (s *LyricsService) checkAccess(ctx context.Context, req Request, track models.Track) error {
+	var user models.User
+	if req.User != nil {
+		user = *req.User
+	}
+
+	if err := authz.CanViewTrack(user, track); err == nil {
+		return nil
+	}
+
+	if track.Hidden || track.Visibility == models.VisibilityPrivate || req.ShareToken == "" || s.shareVerifier == nil {
+		return authz.ErrForbidden
+	}
+
+	return s.shareVerifier.ValidateShare(ctx, req.ShareToken, track.ID, false)
+}
@@ -0,0 +1,334 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tags"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+var (
+	ErrTrackNotFound = errors.New("track not found")
+	ErrEmptyTitle    = errors.New("title must not be empty")
+	ErrVisibility    = errors.New("invalid visibility")
+	ErrPreviewStart  = errors.New("invalid preview start")
+	ErrGenreNotFound = errors.New("genre not found")
+	ErrInvalidTags   = errors.New("invalid tags")
+)
+
+type ManageService struct {
+	log *slog.Logger
+
+	trackProvider   TrackProvider
+	taskProducer    TaskProducer
+	previewProducer PreviewProducer
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+	EditTrack(ctx context.Context, id int64, title string) error
+	DeleteTrack(ctx context.Context, id int64) error
+	SetTrackHidden(ctx context.Context, id int64, hidden bool) error
+	SetTrackVisibility(ctx context.Context, id int64, visibility string) error
+	ResetStatusPending(ctx context.Context, id int64) error
+	SetPreviewStart(ctx context.Context, id int64, start *time.Duration) error
+	GenreBySlug(ctx context.Context, slug string) (models.Genre, error)
+	SetTrackGenre(ctx context.Context, id int64, genreID *int64) error
+	SetTrackTags(ctx context.Context, id int64, tags []string) error
+}
+
+type TaskProducer interface {
+	SendTrackTask(ctx context.Context, trackId string) error
+}
+
+type PreviewProducer interface {
+	SendPreviewTask(ctx context.Context, trackId string) error
+}
+
+// New creates a manage service. A changed preview window is only stored, not cut, when previewProducer is nil.
+func New(log *slog.Logger, trackProvider TrackProvider, taskProducer TaskProducer, previewProducer PreviewProducer) *ManageService {
+	return &ManageService{
+		log:             log,
+		trackProvider:   trackProvider,
+		taskProducer:    taskProducer,
+		previewProducer: previewProducer,
+	}
+}
+
+func (s *ManageService) EditTrack(ctx context.Context, user models.User, id int64, title string) error {
+	const op = "manage.EditTrack"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+	}
+
+	track, err := s.getTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanEditTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.EditTrack(ctx, id, title); err != nil {
+		return fmt.Errorf("%s: failed to edit track: %w", op, err)
+	}
+
+	log.Info("track edited")
+
+	return nil
+}
+
+func (s *ManageService) DeleteTrack(ctx context.Context, user models.User, id int64) error {
+	const op = "manage.DeleteTrack"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	track, err := s.getTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanDeleteTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.DeleteTrack(ctx, id); err != nil {
+		return fmt.Errorf("%s: failed to delete track: %w", op, err)
+	}
+
+	log.Info("track deleted")
+
+	return nil
+}
+
+func (s *ManageService) HideTrack(ctx context.Context, user models.User, id int64, hidden bool) error {
+	const op = "manage.HideTrack"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	if err := authz.CanHideTrack(user); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.getTrack(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.SetTrackHidden(ctx, id, hidden); err != nil {
+		return fmt.Errorf("%s: failed to set hidden: %w", op, err)
+	}
+
+	log.Info("track visibility changed", slog.Bool("hidden", hidden))
+
+	return nil
+}
+
+func (s *ManageService) SetVisibility(ctx context.Context, user models.User, id int64, visibility string) error {
+	const op = "manage.SetVisibility"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	if !authz.ValidVisibility(visibility) {
+		return fmt.Errorf("%s: %w", op, ErrVisibility)
+	}
+
+	track, err := s.getTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanShareTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.SetTrackVisibility(ctx, id, visibility); err != nil {
+		return fmt.Errorf("%s: failed to set visibility: %w", op, err)
+	}
+
+	log.Info("track visibility changed", slog.String("visibility", visibility))
+
+	return nil
+}
+
+// SetPreviewStart moves the preview window of the track, nil resets it to the middle of the track.
+// The preview of a ready track is cut again, a track still processing picks the window up when it's done.
+func (s *ManageService) SetPreviewStart(ctx context.Context, user models.User, id int64, start *time.Duration) error {
+	const op = "manage.SetPreviewStart"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	if start != nil && *start < 0 {
+		return fmt.Errorf("%s: %w", op, ErrPreviewStart)
+	}
+
+	track, err := s.getTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanEditTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.SetPreviewStart(ctx, id, start); err != nil {
+		return fmt.Errorf("%s: failed to set preview start: %w", op, err)
+	}
+
+	if s.previewProducer != nil && track.Status == storage.StatusReady {
+		if err := s.previewProducer.SendPreviewTask(ctx, fmt.Sprintf("%d", id)); err != nil {
+			return fmt.Errorf("%s: failed to send task: %w", op, err)
+		}
+	}
+
+	log.Info("preview start changed")
+
+	return nil
+}
+
+// SetGenre files the track under the genre with the slug, an empty slug removes its genre.
+func (s *ManageService) SetGenre(ctx context.Context, user models.User, id int64, slug string) error {
+	const op = "manage.SetGenre"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	track, err := s.getTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanEditTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var genreID *int64
+	if slug != "" {
+		genre, err := s.trackProvider.GenreBySlug(ctx, slug)
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrGenreNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to get genre: %w", op, err)
+		}
+		genreID = &genre.ID
+	}
+
+	if err := s.trackProvider.SetTrackGenre(ctx, id, genreID); err != nil {
+		return fmt.Errorf("%s: failed to set genre: %w", op, err)
+	}
+
+	log.Info("track genre changed", slog.String("genre", slug))
+
+	return nil
+}
+
+// SetTags replaces the tags of the track, they are normalized first.
+func (s *ManageService) SetTags(ctx context.Context, user models.User, id int64, raw []string) error {
+	const op = "manage.SetTags"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	normalized, err := tags.Normalize(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrInvalidTags, err)
+	}
+
+	track, err := s.getTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanEditTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.SetTrackTags(ctx, id, normalized); err != nil {
+		return fmt.Errorf("%s: failed to set tags: %w", op, err)
+	}
+
+	log.Info("track tags changed", slog.Int("tags", len(normalized)))
+
+	return nil
+}
+
+// ReprocessTrack sends a ready or failed track back to the worker.
+func (s *ManageService) ReprocessTrack(ctx context.Context, user models.User, id int64) error {
+	const op = "manage.ReprocessTrack"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	if err := authz.CanAdminister(user); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.getTrack(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.trackProvider.ResetStatusPending(ctx, id); err != nil {
+		return fmt.Errorf("%s: failed to reset status: %w", op, err)
+	}
+
+	if err := s.taskProducer.SendTrackTask(ctx, fmt.Sprintf("%d", id)); err != nil {
+		return fmt.Errorf("%s: failed to send task: %w", op, err)
+	}
+
+	log.Info("track sent to reprocessing")
+
+	return nil
+}
+
+func (s *ManageService) getTrack(ctx context.Context, id int64) (models.Track, error) {
+	track, err := s.trackProvider.GetTrack(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.Track{}, ErrTrackNotFound
+	}
+	if err != nil {
+		return models.Track{}, fmt.Errorf("failed to get track: %w", err)
+	}
+
+	return track, nil
+}
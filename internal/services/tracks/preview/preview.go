@@ -0,0 +1,147 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+type PreviewService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	mediaProvider MediaProvider
+
+	bucket  string
+	profile Profile
+}
+
+// Profile describes the preview rendition. Tracks shorter than Length are previewed whole,
+// FMP4 and DASH match the full rendition.
+type Profile struct {
+	Length         time.Duration
+	SegmentSeconds int
+	FMP4           bool
+	DASH           bool
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+	SetPreview(ctx context.Context, id int64, previewPrefix string) error
+}
+
+type MediaProvider interface {
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
+	PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error
+	ListObjects(ctx context.Context, bucketName, prefix string) ([]storage.ObjectInfo, error)
+	RemoveObjects(ctx context.Context, bucketName string, objectNames []string) error
+}
+
+func New(log *slog.Logger, trackProvider TrackProvider, mediaProvider MediaProvider, bucket string, profile Profile) *PreviewService {
+	return &PreviewService{
+		log:           log,
+		trackProvider: trackProvider,
+		mediaProvider: mediaProvider,
+		bucket:        bucket,
+		profile:       profile,
+	}
+}
+
+// Generate cuts the preview window of the original file and stores it as an unencrypted HLS rendition
+// next to the full one. The window starts where the owner picked or in the middle of the track.
+func (s *PreviewService) Generate(ctx context.Context, id int64) error {
+	const op = "preview.Generate"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+	)
+
+	track, err := s.trackProvider.GetTrack(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("preview-%d-*", id))
+	if err != nil {
+		return fmt.Errorf("%s: failed to create temp dir: %w", op, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localOriginal := filepath.Join(tmpDir, "original"+filepath.Ext(track.OriginKey))
+	hlsLocalDir := filepath.Join(tmpDir, "preview")
+	if err := os.Mkdir(hlsLocalDir, 0755); err != nil {
+		return fmt.Errorf("%s: failed to create temp dir: %w", op, err)
+	}
+
+	body, _, err := s.mediaProvider.GetObject(ctx, track.OriginBucket, track.OriginKey, nil)
+	if err != nil {
+		return fmt.Errorf("%s: failed to download original track: %w", op, err)
+	}
+	defer body.Close()
+
+	if err := media.WriteToFile(localOriginal, body); err != nil {
+		return fmt.Errorf("%s: failed to save original track to local file: %w", op, err)
+	}
+
+	duration, err := media.ProbeDuration(ctx, localOriginal)
+	if err != nil {
+		return fmt.Errorf("%s: failed to probe duration: %w", op, err)
+	}
+
+	start, length := s.window(duration, track.PreviewStart)
+
+	log.Info("cutting preview", slog.String("start", start.String()), slog.String("length", length.String()))
+
+	opts := media.HLSOptions{
+		SegmentSeconds: s.profile.SegmentSeconds,
+		Codec:          media.CodecAAC,
+		FMP4:           s.profile.FMP4,
+		DASH:           s.profile.DASH,
+	}
+	if err := media.ToHLSClip(ctx, localOriginal, hlsLocalDir, opts, start, length); err != nil {
+		return fmt.Errorf("%s: failed to convert to hls: %w", op, err)
+	}
+
+	prefix := media.GenerateTrackPreviewKey(id, media.NewRun(), start)
+	if err := media.UploadDir(ctx, s.mediaProvider, s.bucket, hlsLocalDir, prefix); err != nil {
+		return fmt.Errorf("%s: failed to upload preview files: %w", op, err)
+	}
+
+	if err := s.trackProvider.SetPreview(ctx, id, prefix); err != nil {
+		return fmt.Errorf("%s: failed to save preview info: %w", op, err)
+	}
+
+	log.Info("preview stored", slog.String("prefix", prefix))
+
+	if track.PreviewPrefix != nil {
+		if err := media.RemovePrefix(ctx, s.mediaProvider, s.bucket, *track.PreviewPrefix); err != nil {
+			log.Error("failed to remove previous preview", slog.String("prefix", *track.PreviewPrefix), slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// window clamps the requested start so the whole preview fits into the track.
+func (s *PreviewService) window(duration time.Duration, requested *time.Duration) (time.Duration, time.Duration) {
+	length := s.profile.Length
+	if duration <= length {
+		return 0, duration
+	}
+
+	latest := duration - length
+	if requested == nil {
+		return (latest / 2).Truncate(time.Millisecond), length
+	}
+
+	return min(max(*requested, 0), latest).Truncate(time.Millisecond), length
+}
@@ -0,0 +1,299 @@
+package shares
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+var (
+	ErrTrackNotFound = errors.New("track not found")
+	ErrShareNotFound = errors.New("share link not found")
+	ErrInvalidShare  = errors.New("invalid share link")
+	ErrPrivateTrack  = errors.New("private tracks can't be shared")
+	ErrInvalidLimits = errors.New("invalid share limits")
+)
+
+// lastPlayGrace is how long after the end of the track the last play of a link may still fetch files.
+const lastPlayGrace = 10 * time.Minute
+
+type ShareService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	shareStore    ShareStore
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+}
+
+type ShareStore interface {
+	SaveShareLink(ctx context.Context, trackID, createdBy int64, tokenHash []byte, expiresAt *time.Time, maxPlays *int) (models.ShareLink, error)
+	ShareLinkByHash(ctx context.Context, tokenHash []byte) (models.ShareLink, error)
+	ListShareLinks(ctx context.Context, trackID int64, limit int, after *models.Cursor) ([]models.ShareLink, error)
+	RevokeShareLink(ctx context.Context, id int64, trackID int64) error
+	ConsumeSharePlay(ctx context.Context, id int64) error
+}
+
+func New(log *slog.Logger, trackProvider TrackProvider, shareStore ShareStore) *ShareService {
+	return &ShareService{
+		log:           log,
+		trackProvider: trackProvider,
+		shareStore:    shareStore,
+	}
+}
+
+// CreateShare issues a share link for the track. The plain token is returned only once and never stored.
+func (s *ShareService) CreateShare(ctx context.Context, user models.User, trackID int64, expiresAt *time.Time, maxPlays *int) (models.ShareLink, string, error) {
+	const op = "shares.CreateShare"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", trackID),
+		slog.Int64("user_id", user.ID),
+	)
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return models.ShareLink{}, "", fmt.Errorf("%s: %w: expiry is in the past", op, ErrInvalidLimits)
+	}
+	if maxPlays != nil && *maxPlays <= 0 {
+		return models.ShareLink{}, "", fmt.Errorf("%s: %w: max plays must be positive", op, ErrInvalidLimits)
+	}
+
+	track, err := s.getTrack(ctx, trackID)
+	if err != nil {
+		return models.ShareLink{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanShareTrack(user, track); err != nil {
+		return models.ShareLink{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if track.Visibility == models.VisibilityPrivate {
+		return models.ShareLink{}, "", fmt.Errorf("%s: %w", op, ErrPrivateTrack)
+	}
+
+	raw, hash, err := generateToken()
+	if err != nil {
+		return models.ShareLink{}, "", fmt.Errorf("%s: failed to generate token: %w", op, err)
+	}
+
+	link, err := s.shareStore.SaveShareLink(ctx, trackID, user.ID, hash, expiresAt, maxPlays)
+	if err != nil {
+		return models.ShareLink{}, "", fmt.Errorf("%s: failed to save share link: %w", op, err)
+	}
+
+	log.Info("share link created", slog.Int64("share_id", link.ID))
+
+	return link, raw, nil
+}
+
+// ListShares returns a page of up to limit links of the track, newest first, and the cursor of the next page,
+// nil on the last one.
+func (s *ShareService) ListShares(ctx context.Context, user models.User, trackID int64, limit int, after *models.Cursor) ([]models.ShareLink, *models.Cursor, error) {
+	const op = "shares.ListShares"
+
+	track, err := s.getTrack(ctx, trackID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanShareTrack(user, track); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// One more link than asked tells whether there is a next page.
+	links, err := s.shareStore.ListShareLinks(ctx, trackID, limit+1, after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to list share links: %w", op, err)
+	}
+
+	if len(links) <= limit {
+		return links, nil, nil
+	}
+
+	links = links[:limit]
+	last := links[limit-1]
+
+	return links, &models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
+func (s *ShareService) RevokeShare(ctx context.Context, user models.User, trackID int64, id int64) error {
+	const op = "shares.RevokeShare"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", trackID),
+		slog.Int64("share_id", id),
+		slog.Int64("user_id", user.ID),
+	)
+
+	track, err := s.getTrack(ctx, trackID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authz.CanShareTrack(user, track); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.shareStore.RevokeShareLink(ctx, id, trackID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrShareNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to revoke share link: %w", op, err)
+	}
+
+	log.Info("share link revoked")
+
+	return nil
+}
+
+// ResolveShare returns the track the link points to without counting a play.
+func (s *ShareService) ResolveShare(ctx context.Context, raw string) (models.Track, error) {
+	const op = "shares.ResolveShare"
+
+	link, err := s.activeLink(ctx, raw)
+	if err != nil {
+		return models.Track{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if link.MaxPlays != nil && link.Plays >= *link.MaxPlays {
+		return models.Track{}, fmt.Errorf("%s: %w: no plays left", op, ErrInvalidShare)
+	}
+
+	track, err := s.getTrack(ctx, link.TrackID)
+	if err != nil {
+		return models.Track{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if track.Hidden || track.Visibility == models.VisibilityPrivate {
+		return models.Track{}, fmt.Errorf("%s: %w", op, ErrInvalidShare)
+	}
+
+	return track, nil
+}
+
+// ValidateShare checks that the link grants access to the track. With consume set a play is counted,
+// which fails once the play limit of the link is reached. Without it a link out of plays is only
+// accepted while its last play may still be going on, so the segments of that play can be fetched.
+func (s *ShareService) ValidateShare(ctx context.Context, raw string, trackID int64, consume bool) error {
+	const op = "shares.ValidateShare"
+
+	link, err := s.activeLink(ctx, raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if link.TrackID != trackID {
+		return fmt.Errorf("%s: %w: link belongs to another track", op, ErrInvalidShare)
+	}
+
+	if !consume {
+		if link.MaxPlays == nil || link.Plays < *link.MaxPlays {
+			return nil
+		}
+
+		playing, err := s.lastPlayGoingOn(ctx, link)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !playing {
+			return fmt.Errorf("%s: %w: no plays left", op, ErrInvalidShare)
+		}
+
+		return nil
+	}
+
+	err = s.shareStore.ConsumeSharePlay(ctx, link.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w: no plays left", op, ErrInvalidShare)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to count play: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *ShareService) activeLink(ctx context.Context, raw string) (models.ShareLink, error) {
+	if raw == "" {
+		return models.ShareLink{}, ErrInvalidShare
+	}
+
+	link, err := s.shareStore.ShareLinkByHash(ctx, hashToken(raw))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.ShareLink{}, ErrInvalidShare
+	}
+	if err != nil {
+		return models.ShareLink{}, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	if link.RevokedAt != nil {
+		return models.ShareLink{}, fmt.Errorf("%w: revoked", ErrInvalidShare)
+	}
+	if link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now()) {
+		return models.ShareLink{}, fmt.Errorf("%w: expired", ErrInvalidShare)
+	}
+
+	return link, nil
+}
+
+// lastPlayGoingOn reports whether the last counted play of the link can still be going on, that is
+// whether the track could still be playing, paused and seeked included, since the play was counted.
+func (s *ShareService) lastPlayGoingOn(ctx context.Context, link models.ShareLink) (bool, error) {
+	if link.LastPlayedAt == nil {
+		return false, nil
+	}
+
+	track, err := s.getTrack(ctx, link.TrackID)
+	if err != nil {
+		return false, err
+	}
+
+	window := lastPlayGrace
+	if track.Duration != nil {
+		window += *track.Duration
+	}
+
+	return time.Since(*link.LastPlayedAt) < window, nil
+}
+
+func (s *ShareService) getTrack(ctx context.Context, id int64) (models.Track, error) {
+	track, err := s.trackProvider.GetTrack(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.Track{}, ErrTrackNotFound
+	}
+	if err != nil {
+		return models.Track{}, fmt.Errorf("failed to get track: %w", err)
+	}
+
+	return track, nil
+}
+
+func generateToken() (string, []byte, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(b)
+
+	return raw, hashToken(raw), nil
+}
+
+func hashToken(raw string) []byte {
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:]
+}
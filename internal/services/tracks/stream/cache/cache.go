@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrInvalidRange = errors.New("invalid byte range")
+
+	errTooLarge = errors.New("object is too large to cache")
+)
+
+const fetchTimeout = 30 * time.Second
+
+type MediaProvider interface {
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
+}
+
+type Config struct {
+	MemoryLimit   int64
+	MaxObjectSize int64
+	TTL           time.Duration
+	PlaylistTTL   time.Duration
+	DiskDir       string
+	DiskLimit     int64
+}
+
+type Stats struct {
+	Hits        uint64
+	DiskHits    uint64
+	Misses      uint64
+	Evictions   uint64
+	MemoryBytes int64
+	MemoryItems int
+	DiskBytes   int64
+	DiskItems   int
+}
+
+// Cache is a size-bounded LRU cache of HLS objects that sits in front of the media provider.
+// Objects evicted from memory are spilled to disk when a disk directory is configured.
+type Cache struct {
+	log *slog.Logger
+
+	mediaProvider MediaProvider
+	cfg           Config
+
+	group singleflight.Group
+
+	mu          sync.Mutex
+	items       map[string]*list.Element
+	lru         *list.List
+	memoryBytes int64
+
+	disk *diskStore
+
+	hits      atomic.Uint64
+	diskHits  atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type entry struct {
+	key       string
+	data      []byte
+	info      storage.ObjectInfo
+	expiresAt time.Time
+}
+
+func New(log *slog.Logger, mediaProvider MediaProvider, cfg Config) (*Cache, error) {
+	const op = "stream.cache.New"
+
+	c := &Cache{
+		log:           log,
+		mediaProvider: mediaProvider,
+		cfg:           cfg,
+		items:         make(map[string]*list.Element),
+		lru:           list.New(),
+	}
+
+	if cfg.DiskDir != "" && cfg.DiskLimit > 0 {
+		disk, err := newDiskStore(cfg.DiskDir, cfg.DiskLimit)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to init disk store: %w", op, err)
+		}
+		c.disk = disk
+	}
+
+	return c, nil
+}
+
+// GetObject serves the object from the cache, fetching it from the media provider on a miss.
+// Concurrent misses for the same object share a single fetch.
+func (c *Cache) GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error) {
+	const op = "stream.cache.GetObject"
+
+	key := bucketName + "/" + objectName
+
+	if e, ok := c.lookup(key); ok {
+		return serve(e, byteRange)
+	}
+
+	c.misses.Add(1)
+
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		return c.fetch(ctx, key, bucketName, objectName)
+	})
+	if errors.Is(err, errTooLarge) {
+		return c.mediaProvider.GetObject(ctx, bucketName, objectName, byteRange)
+	}
+	if err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return serve(v.(*entry), byteRange)
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	st := Stats{
+		MemoryBytes: c.memoryBytes,
+		MemoryItems: c.lru.Len(),
+	}
+	c.mu.Unlock()
+
+	if c.disk != nil {
+		st.DiskBytes, st.DiskItems = c.disk.usage()
+	}
+
+	st.Hits = c.hits.Load()
+	st.DiskHits = c.diskHits.Load()
+	st.Misses = c.misses.Load()
+	st.Evictions = c.evictions.Load()
+
+	return st
+}
+
+func (c *Cache) lookup(key string) (*entry, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		if e.expired(now) {
+			c.removeElement(el)
+			c.mu.Unlock()
+			return nil, false
+		}
+
+		c.lru.MoveToFront(el)
+		c.mu.Unlock()
+
+		c.hits.Add(1)
+		return e, true
+	}
+	c.mu.Unlock()
+
+	if c.disk == nil {
+		return nil, false
+	}
+
+	e, ok := c.disk.take(key, now)
+	if !ok {
+		return nil, false
+	}
+
+	c.diskHits.Add(1)
+	c.store(e)
+
+	return e, true
+}
+
+func (c *Cache) fetch(ctx context.Context, key, bucketName, objectName string) (*entry, error) {
+	// The fetch is shared with other waiters, so it must not be cancelled by the first caller going away.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+	defer cancel()
+
+	rc, info, err := c.mediaProvider.GetObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	if c.cfg.MaxObjectSize > 0 && info.Size > c.cfg.MaxObjectSize {
+		return nil, errTooLarge
+	}
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	e := &entry{
+		key:  key,
+		data: data,
+		info: info,
+	}
+
+	if ttl := c.ttlFor(objectName); ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.store(e)
+
+	return e, nil
+}
+
+func (c *Cache) store(e *entry) {
+	size := int64(len(e.data))
+	if size > c.cfg.MemoryLimit {
+		return
+	}
+
+	var evicted []*entry
+
+	c.mu.Lock()
+	if el, ok := c.items[e.key]; ok {
+		c.removeElement(el)
+	}
+
+	c.items[e.key] = c.lru.PushFront(e)
+	c.memoryBytes += size
+
+	for c.memoryBytes > c.cfg.MemoryLimit {
+		el := c.lru.Back()
+		if el == nil {
+			break
+		}
+		evicted = append(evicted, el.Value.(*entry))
+		c.removeElement(el)
+	}
+	c.mu.Unlock()
+
+	c.evictions.Add(uint64(len(evicted)))
+
+	if c.disk == nil {
+		return
+	}
+
+	for _, ev := range evicted {
+		if err := c.disk.put(ev); err != nil {
+			c.log.Warn("failed to spill cache entry to disk", slog.String("key", ev.key), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// removeElement must be called with c.mu held.
+func (c *Cache) removeElement(el *list.Element) {
+	e := el.Value.(*entry)
+	c.lru.Remove(el)
+	delete(c.items, e.key)
+	c.memoryBytes -= int64(len(e.data))
+}
+
+// ttlFor returns how long the object is cached. Manifests get the playlist TTL, segments and init
+// fragments never change under their key, a reprocessed track is stored under a new prefix.
+func (c *Cache) ttlFor(objectName string) time.Duration {
+	switch strings.ToLower(filepath.Ext(objectName)) {
+	case ".m3u8", ".mpd":
+		return c.cfg.PlaylistTTL
+	default:
+		return c.cfg.TTL
+	}
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+func serve(e *entry, br *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error) {
+	if br == nil {
+		return io.NopCloser(bytes.NewReader(e.data)), e.info, nil
+	}
+
+	size := int64(len(e.data))
+	end := br.End
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+	if br.Start >= size || br.Start > end {
+		return nil, storage.ObjectInfo{}, ErrInvalidRange
+	}
+
+	return io.NopCloser(bytes.NewReader(e.data[br.Start : end+1])), e.info, nil
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// fakeProvider serves objects from a map and counts the fetches. With release set every fetch waits for it.
+type fakeProvider struct {
+	objects map[string][]byte
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (p *fakeProvider) GetObject(_ context.Context, _, objectName string, _ *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+
+	data, ok := p.objects[objectName]
+	if !ok {
+		return nil, storage.ObjectInfo{}, storage.ErrNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), storage.ObjectInfo{Key: objectName, Size: int64(len(data)), ETag: objectName}, nil
+}
+
+func newCache(t *testing.T, provider *fakeProvider, cfg Config) *Cache {
+	t.Helper()
+
+	c, err := New(slog.New(slog.DiscardHandler), provider, cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func get(t *testing.T, c *Cache, name string, br *storage.ByteRange) []byte {
+	t.Helper()
+
+	rc, _, err := c.GetObject(t.Context(), "hls", name, br)
+	if err != nil {
+		t.Fatalf("GetObject(%s): %v", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return data
+}
+
+func TestLRUEviction(t *testing.T) {
+	provider := &fakeProvider{objects: map[string][]byte{"a.aac": []byte("aaaa"), "b.aac": []byte("bbbb"), "c.aac": []byte("cccc")}}
+	c := newCache(t, provider, Config{MemoryLimit: 10})
+
+	get(t, c, "a.aac", nil)
+	get(t, c, "b.aac", nil)
+	get(t, c, "a.aac", nil)
+	get(t, c, "c.aac", nil)
+
+	if calls := provider.calls.Load(); calls != 3 {
+		t.Fatalf("fetches = %d, want 3", calls)
+	}
+
+	// b was the least recently used entry when c didn't fit anymore.
+	get(t, c, "a.aac", nil)
+	if calls := provider.calls.Load(); calls != 3 {
+		t.Fatalf("a was evicted, fetches = %d", calls)
+	}
+	get(t, c, "b.aac", nil)
+	if calls := provider.calls.Load(); calls != 4 {
+		t.Fatalf("b was kept, fetches = %d", calls)
+	}
+
+	st := c.Stats()
+	if st.MemoryBytes > 10 || st.Evictions != 2 {
+		t.Fatalf("Stats() = %+v, want at most 10 bytes after 2 evictions", st)
+	}
+}
+
+func TestObjectsOverLimitAreNotCached(t *testing.T) {
+	provider := &fakeProvider{objects: map[string][]byte{"big.aac": []byte("0123456789")}}
+	c := newCache(t, provider, Config{MemoryLimit: 100, MaxObjectSize: 4})
+
+	for range 2 {
+		if got := get(t, c, "big.aac", nil); string(got) != "0123456789" {
+			t.Fatalf("GetObject() = %q", got)
+		}
+	}
+
+	// The first fetch finds the object too large and the request reads it again uncached.
+	if calls := provider.calls.Load(); calls != 4 {
+		t.Fatalf("fetches = %d, want 4", calls)
+	}
+	if st := c.Stats(); st.MemoryItems != 0 {
+		t.Fatalf("Stats() = %+v, want nothing cached", st)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c := newCache(t, &fakeProvider{}, Config{TTL: time.Hour, PlaylistTTL: 10 * time.Second})
+
+	tests := []struct {
+		name string
+		want time.Duration
+	}{
+		{name: "index.m3u8", want: 10 * time.Second},
+		{name: "manifest.MPD", want: 10 * time.Second},
+		{name: "segment_000.aac", want: time.Hour},
+		{name: "init.mp4", want: time.Hour},
+		{name: "chunk_1.m4s", want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := c.ttlFor(tt.name); got != tt.want {
+			t.Errorf("ttlFor(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlaylistExpiresBeforeSegments(t *testing.T) {
+	provider := &fakeProvider{objects: map[string][]byte{"index.m3u8": []byte("#EXTM3U"), "segment_000.aac": []byte("aac")}}
+	c := newCache(t, provider, Config{MemoryLimit: 100, TTL: time.Hour, PlaylistTTL: time.Millisecond})
+
+	get(t, c, "index.m3u8", nil)
+	get(t, c, "segment_000.aac", nil)
+	time.Sleep(5 * time.Millisecond)
+	get(t, c, "index.m3u8", nil)
+	get(t, c, "segment_000.aac", nil)
+
+	if calls := provider.calls.Load(); calls != 3 {
+		t.Fatalf("fetches = %d, want the playlist fetched twice and the segment once", calls)
+	}
+}
+
+func TestConcurrentMissesShareFetch(t *testing.T) {
+	provider := &fakeProvider{objects: map[string][]byte{"segment_000.aac": []byte("aac")}, release: make(chan struct{})}
+	c := newCache(t, provider, Config{MemoryLimit: 100})
+
+	const listeners = 8
+
+	var wg sync.WaitGroup
+	results := make([][]byte, listeners)
+	for i := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rc, _, err := c.GetObject(context.Background(), "hls", "segment_000.aac", nil)
+			if err != nil {
+				t.Errorf("GetObject: %v", err)
+				return
+			}
+			defer rc.Close()
+			results[i], _ = io.ReadAll(rc)
+		}()
+	}
+
+	// Every listener has missed before the single fetch is let through.
+	for c.Stats().Misses < listeners {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("fetches = %d, want 1", calls)
+	}
+	for i, got := range results {
+		if string(got) != "aac" {
+			t.Fatalf("listener %d got %q", i, got)
+		}
+	}
+}
+
+func TestSpillToDisk(t *testing.T) {
+	parent := t.TempDir()
+	keep := filepath.Join(parent, "keep")
+	if err := os.WriteFile(keep, []byte("not the cache's"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	provider := &fakeProvider{objects: map[string][]byte{"a.aac": []byte("aaaa"), "b.aac": []byte("bbbb")}}
+	c := newCache(t, provider, Config{MemoryLimit: 4, DiskDir: parent, DiskLimit: 100})
+
+	if _, err := os.Stat(keep); err != nil {
+		t.Fatalf("the cache removed a file it doesn't own: %v", err)
+	}
+
+	get(t, c, "a.aac", nil)
+	get(t, c, "b.aac", nil)
+
+	if st := c.Stats(); st.DiskItems != 1 || st.DiskBytes != 4 {
+		t.Fatalf("Stats() = %+v, want a on disk", st)
+	}
+
+	if got := get(t, c, "a.aac", &storage.ByteRange{Start: 1, End: 2}); string(got) != "aa" {
+		t.Fatalf("GetObject() from disk = %q, want aa", got)
+	}
+
+	st := c.Stats()
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Fatalf("fetches = %d, want a served from disk", calls)
+	}
+	if st.DiskHits != 1 || st.DiskItems != 1 || st.MemoryItems != 1 {
+		t.Fatalf("Stats() = %+v, want a back in memory and b on disk", st)
+	}
+
+	files, err := os.ReadDir(filepath.Join(parent, diskSubdir))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("disk cache holds %d files, want 1", len(files))
+	}
+}
+
+func TestInvalidRange(t *testing.T) {
+	provider := &fakeProvider{objects: map[string][]byte{"a.aac": []byte("aaaa")}}
+	c := newCache(t, provider, Config{MemoryLimit: 100})
+
+	_, _, err := c.GetObject(t.Context(), "hls", "a.aac", &storage.ByteRange{Start: 4, End: -1})
+	if !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("GetObject() error = %v, want %v", err, ErrInvalidRange)
+	}
+}
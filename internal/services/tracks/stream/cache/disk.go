@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// diskSubdir is the directory the cache owns inside the configured one. The configured directory may be
+// shared with anything else, only this subdirectory is ever wiped.
+const diskSubdir = "segment-cache"
+
+// diskStore keeps entries evicted from memory as files in a local directory.
+// The index lives in memory only, so the directory is wiped on start.
+type diskStore struct {
+	dir   string
+	limit int64
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List
+	bytes int64
+}
+
+type diskEntry struct {
+	key  string
+	path string
+	size int64
+	meta *entry
+}
+
+func newDiskStore(parent string, limit int64) (*diskStore, error) {
+	dir := filepath.Join(parent, diskSubdir)
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &diskStore{
+		dir:   dir,
+		limit: limit,
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+	}, nil
+}
+
+func (d *diskStore) put(e *entry) error {
+	size := int64(len(e.data))
+	if size > d.limit {
+		return nil
+	}
+
+	path, err := d.write(e)
+	if err != nil {
+		return err
+	}
+
+	meta := &entry{
+		key:       e.key,
+		info:      e.info,
+		expiresAt: e.expiresAt,
+	}
+
+	var stale []string
+
+	d.mu.Lock()
+	if el, ok := d.items[e.key]; ok {
+		stale = append(stale, el.Value.(*diskEntry).path)
+		d.removeElement(el)
+	}
+
+	d.items[e.key] = d.lru.PushFront(&diskEntry{key: e.key, path: path, size: size, meta: meta})
+	d.bytes += size
+
+	for d.bytes > d.limit {
+		el := d.lru.Back()
+		if el == nil {
+			break
+		}
+		stale = append(stale, el.Value.(*diskEntry).path)
+		d.removeElement(el)
+	}
+	d.mu.Unlock()
+
+	for _, p := range stale {
+		_ = os.Remove(p)
+	}
+
+	return nil
+}
+
+// write stores the entry data in a file of its own. Every put gets a new file, so once an entry leaves the index
+// under the lock its file belongs to whoever removed it and a concurrent put of the same key can't touch it.
+func (d *diskStore) write(e *entry) (string, error) {
+	sum := sha256.Sum256([]byte(e.key))
+
+	f, err := os.CreateTemp(d.dir, hex.EncodeToString(sum[:])+"-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %w", err)
+	}
+
+	if _, err := f.Write(e.data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	return f.Name(), nil
+}
+
+// take removes the entry from disk and returns it with its data loaded, so it can be promoted back to memory.
+func (d *diskStore) take(key string, now time.Time) (*entry, bool) {
+	d.mu.Lock()
+	el, ok := d.items[key]
+	if !ok {
+		d.mu.Unlock()
+		return nil, false
+	}
+	de := el.Value.(*diskEntry)
+	d.removeElement(el)
+	d.mu.Unlock()
+
+	defer os.Remove(de.path)
+
+	if de.meta.expired(now) {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(de.path)
+	if err != nil {
+		return nil, false
+	}
+
+	return &entry{
+		key:       de.key,
+		data:      data,
+		info:      de.meta.info,
+		expiresAt: de.meta.expiresAt,
+	}, true
+}
+
+func (d *diskStore) usage() (int64, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.bytes, d.lru.Len()
+}
+
+// removeElement must be called with d.mu held.
+func (d *diskStore) removeElement(el *list.Element) {
+	de := el.Value.(*diskEntry)
+	d.lru.Remove(el)
+	delete(d.items, de.key)
+	d.bytes -= de.size
+}
@@ -0,0 +1,47 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	requestsDesc = prometheus.NewDesc(
+		"music_stream_cache_requests_total",
+		"Stream cache lookups by result.",
+		[]string{"result"}, nil,
+	)
+	evictionsDesc = prometheus.NewDesc(
+		"music_stream_cache_evictions_total",
+		"Objects evicted from the memory cache.",
+		nil, nil,
+	)
+	bytesDesc = prometheus.NewDesc(
+		"music_stream_cache_bytes",
+		"Bytes held by the cache tier.",
+		[]string{"tier"}, nil,
+	)
+	itemsDesc = prometheus.NewDesc(
+		"music_stream_cache_items",
+		"Objects held by the cache tier.",
+		[]string{"tier"}, nil,
+	)
+)
+
+// Describe and Collect expose Stats as Prometheus metrics.
+func (c *Cache) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- evictionsDesc
+	ch <- bytesDesc
+	ch <- itemsDesc
+}
+
+func (c *Cache) Collect(ch chan<- prometheus.Metric) {
+	st := c.Stats()
+
+	ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(st.Hits), "memory_hit")
+	ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(st.DiskHits), "disk_hit")
+	ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(st.Misses), "miss")
+	ch <- prometheus.MustNewConstMetric(evictionsDesc, prometheus.CounterValue, float64(st.Evictions))
+	ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.GaugeValue, float64(st.MemoryBytes), "memory")
+	ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.GaugeValue, float64(st.DiskBytes), "disk")
+	ch <- prometheus.MustNewConstMetric(itemsDesc, prometheus.GaugeValue, float64(st.MemoryItems), "memory")
+	ch <- prometheus.MustNewConstMetric(itemsDesc, prometheus.GaugeValue, float64(st.DiskItems), "disk")
+}
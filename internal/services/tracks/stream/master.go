@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	lyricslib "github.com/Sheridanlk/Music-Service/internal/lib/lyrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// Files of the default rendition that are built per request instead of being stored. The master playlist
+// lists the audio playlist and, for tracks with synced lyrics, the WebVTT subtitle rendition made of one cue file.
+const (
+	masterFile         = "master.m3u8"
+	lyricsPlaylistFile = "lyrics.m3u8"
+	lyricsFile         = "lyrics.vtt"
+)
+
+// masterBandwidth is the peak bitrate of the 128k AAC rendition with its container overhead.
+const masterBandwidth = 140000
+
+var ErrNoLyrics = errors.New("track has no synced lyrics")
+
+// LyricsProvider returns the lyrics of a track, storage.ErrNotFound when it has none.
+type LyricsProvider interface {
+	GetLyrics(ctx context.Context, trackID int64) (models.Lyrics, error)
+}
+
+func isGenerated(file string) bool {
+	return file == masterFile || file == lyricsPlaylistFile || file == lyricsFile
+}
+
+// generate builds the master playlist, the subtitle playlist or the cue file. Listeners that only get
+// the preview get a master without lyrics, they wouldn't match the clip.
+func (s *StreamService) generate(ctx context.Context, file string, track models.Track, full bool) (io.ReadCloser, storage.ObjectInfo, error) {
+	var lyrics []lyricslib.Line
+	var language string
+	var info storage.ObjectInfo
+
+	if full && s.lyrics != nil && track.Duration != nil {
+		stored, err := s.lyrics.GetLyrics(ctx, track.ID)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+		case err != nil:
+			return nil, storage.ObjectInfo{}, fmt.Errorf("failed to get lyrics: %w", err)
+		case stored.Format == models.LyricsLRC:
+			lyrics, err = lyricslib.ParseLRC(stored.Text)
+			if err != nil {
+				return nil, storage.ObjectInfo{}, fmt.Errorf("failed to parse lyrics: %w", err)
+			}
+			language = stored.Language
+			info.LastModified = stored.UpdatedAt
+		}
+	}
+
+	var data []byte
+	switch file {
+	case masterFile:
+		// The master lists the lyrics only while they exist, a stale Last-Modified would hide that change.
+		info.LastModified = time.Time{}
+		data = masterPlaylist(lyrics != nil, language)
+	case lyricsPlaylistFile:
+		if lyrics == nil {
+			return nil, storage.ObjectInfo{}, ErrNoLyrics
+		}
+		data = lyricsPlaylist(*track.Duration)
+	case lyricsFile:
+		if lyrics == nil {
+			return nil, storage.ObjectInfo{}, ErrNoLyrics
+		}
+		data = lyricslib.WebVTT(lyrics, *track.Duration)
+	}
+
+	info.Key = file
+	info.Size = int64(len(data))
+	info.ContentType = media.DetectContentType(file)
+
+	return io.NopCloser(bytes.NewReader(data)), info, nil
+}
+
+func masterPlaylist(withLyrics bool, language string) []byte {
+	var out bytes.Buffer
+	out.WriteString("#EXTM3U\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	subtitles := ""
+	if withLyrics {
+		out.WriteString(`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="lyrics",NAME="Lyrics",`)
+		if language != "" {
+			out.WriteString(`LANGUAGE="` + language + `",`)
+		}
+		out.WriteString(`DEFAULT=YES,AUTOSELECT=YES,`)
+		out.WriteString(`URI="` + lyricsPlaylistFile + `"` + "\n")
+		subtitles = `,SUBTITLES="lyrics"`
+	}
+
+	fmt.Fprintf(&out, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"mp4a.40.2\"%s\n%s\n", masterBandwidth, subtitles, indexFile)
+
+	return out.Bytes()
+}
+
+// lyricsPlaylist lists the whole cue file as a single segment spanning the track.
+func lyricsPlaylist(duration time.Duration) []byte {
+	var out bytes.Buffer
+	out.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+	out.WriteString("#EXT-X-TARGETDURATION:" + strconv.Itoa(int(math.Ceil(duration.Seconds()))) + "\n")
+	out.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	fmt.Fprintf(&out, "#EXTINF:%.3f,\n%s\n#EXT-X-ENDLIST\n", duration.Seconds(), lyricsFile)
+
+	return out.Bytes()
+}
@@ -1,19 +1,55 @@
 package stream
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/lib/streamtoken"
 	"github.com/Sheridanlk/Music-Service/internal/storage"
 )
 
+const (
+	tokenParam = "token"
+	shareParam = "share"
+	indexFile  = "index.m3u8"
+	mpdFile    = "manifest.mpd"
+)
+
+// Renditions a listener may ask for. The default one is the full track for listeners
+// entitled to it and the preview for the others.
+const (
+	RenditionDefault  = ""
+	RenditionPreview  = "preview"
+	RenditionLossless = "lossless"
+)
+
+// Request describes a single stream file request. User is nil for anonymous listeners.
+type Request struct {
+	TrackID    int64
+	File       string
+	Token      string
+	ShareToken string
+	User       *models.User
+	Range      *storage.ByteRange
+	Rendition  string
+}
+
 var (
 	ErrTrackNotReady = errors.New("track is not ready")
+	ErrNoPreview     = errors.New("track has no preview")
+	ErrNoLossless    = errors.New("track has no lossless rendition")
 	ErrBadStreamFile = errors.New("bad stream file")
+	ErrAccessDenied  = errors.New("access denied")
 )
 
 type StreamService struct {
@@ -21,56 +57,253 @@ type StreamService struct {
 
 	trackProvider TrackProvider
 	mediaProvider MediaProvider
+	tokenVerifier TokenVerifier
+	shareVerifier ShareVerifier
+	entitlements  EntitlementChecker
+	lyrics        LyricsProvider
+
+	previews bool
 }
 
 type TrackProvider interface {
-	GetHLS(ctx context.Context, id int64) (string, string, error)
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+	IncrementPlays(ctx context.Context, id int64) error
 }
 
 type MediaProvider interface {
-	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, string, int64, error)
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
+}
+
+type TokenVerifier interface {
+	Verify(token string, trackID int64, userID string) (streamtoken.Claims, error)
+}
+
+type ShareVerifier interface {
+	ValidateShare(ctx context.Context, raw string, trackID int64, consume bool) error
+}
+
+// EntitlementChecker decides which logged-in listeners may stream the lossless renditions.
+type EntitlementChecker interface {
+	CheckLossless(ctx context.Context, user models.User) error
 }
 
-func New(log *slog.Logger, trackProvider TrackProvider, mediaProvider MediaProvider) *StreamService {
+// New creates a stream service. Stream tokens are not checked when tokenVerifier is nil, otherwise they must
+// have been issued to the listener of the request. Every
+// logged-in listener may stream lossless renditions when entitlements is nil. The master playlist
+// offers no subtitle rendition when lyrics is nil.
+// With previews, anonymous listeners get the preview rendition of public tracks unless they hold a share link.
+func New(
+	log *slog.Logger,
+	trackProvider TrackProvider,
+	mediaProvider MediaProvider,
+	tokenVerifier TokenVerifier,
+	shareVerifier ShareVerifier,
+	entitlements EntitlementChecker,
+	lyrics LyricsProvider,
+	previews bool,
+) *StreamService {
 	return &StreamService{
 		log:           log,
 		trackProvider: trackProvider,
 		mediaProvider: mediaProvider,
+		tokenVerifier: tokenVerifier,
+		shareVerifier: shareVerifier,
+		entitlements:  entitlements,
+		lyrics:        lyrics,
+		previews:      previews,
 	}
 }
 
-func (s *StreamService) GetStreamObject(ctx context.Context, trackID int64, file string, br *storage.ByteRange) (io.ReadCloser, string, int64, error) {
+func (s *StreamService) GetStreamObject(ctx context.Context, req Request) (io.ReadCloser, storage.ObjectInfo, error) {
 	const op = "stream.GetStreamObject"
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.Int64("track_id", trackID),
-		slog.String("file", file),
+		slog.Int64("track_id", req.TrackID),
+		slog.String("file", req.File),
 	)
 
 	log.Info("getting file")
 
+	file := req.File
 	if file == "" || strings.Contains(file, "..") || strings.ContainsAny(file, `\/`) {
-		return nil, "", 0, fmt.Errorf("%s: %w", op, ErrBadStreamFile)
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrBadStreamFile)
 	}
 
-	bucket, prefix, err := s.trackProvider.GetHLS(ctx, trackID)
+	if s.tokenVerifier != nil {
+		var viewerID int64
+		if req.User != nil {
+			viewerID = req.User.ID
+		}
+
+		if _, err := s.tokenVerifier.Verify(req.Token, req.TrackID, streamtoken.Subject(viewerID)); err != nil {
+			return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+		}
+	}
+
+	track, err := s.trackProvider.GetTrack(ctx, req.TrackID)
 	if err != nil {
-		return nil, "", 0, fmt.Errorf("%s: failed to get hls info: %w", op, err)
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to get track: %w", op, err)
 	}
 
-	if prefix == "" {
-		return nil, "", 0, fmt.Errorf("%s: %w", op, ErrTrackNotReady)
+	full, err := s.checkAccess(ctx, req, track)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
 	}
 
-	key := prefix + file
+	if track.HLSBucket == nil || track.HLSPrefix == nil || *track.HLSPrefix == "" {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrTrackNotReady)
+	}
 
-	rc, ct, size, err := s.mediaProvider.GetObject(ctx, bucket, key, br)
-	if err != nil {
-		return nil, "", 0, fmt.Errorf("%s: failed to get object: %w", op, err)
+	var rc io.ReadCloser
+	var info storage.ObjectInfo
+
+	if req.Rendition == RenditionDefault && isGenerated(file) {
+		rc, info, err = s.generate(ctx, file, track, full)
+		if err != nil {
+			return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, err)
+		}
+	} else {
+		prefix, err := s.renditionPrefix(ctx, req, track, full)
+		if err != nil {
+			return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		rc, info, err = s.mediaProvider.GetObject(ctx, *track.HLSBucket, prefix+file, req.Range)
+		if err != nil {
+			return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to get object: %w", op, err)
+		}
+	}
+
+	if rewrite := manifestRewriter(file); (req.Range == nil || isGenerated(file)) && rewrite != nil {
+		token := ""
+		if s.tokenVerifier != nil {
+			token = req.Token
+		}
+
+		if token != "" || req.ShareToken != "" {
+			rc, info, err = signManifest(rc, info, rewrite, token, req.ShareToken)
+			if err != nil {
+				return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to sign manifest: %w", op, err)
+			}
+		}
+	}
+
+	if countsPlay(req, full) {
+		// A lost play only skews the popularity order, it must not break playback.
+		if err := s.trackProvider.IncrementPlays(ctx, track.ID); err != nil {
+			log.Warn("failed to count play", slog.String("error", err.Error()))
+		}
 	}
 
 	log.Info("file getted")
 
-	return rc, ct, size, nil
+	return rc, info, nil
+}
+
+// checkAccess enforces the track visibility and reports whether the listener may play the full track.
+// Listeners without direct access need a share link, a play is counted each time the link is used
+// to open the media playlist or the DASH manifest. Anonymous listeners only get the preview when previews are enabled.
+func (s *StreamService) checkAccess(ctx context.Context, req Request, track models.Track) (bool, error) {
+	var user models.User
+	if req.User != nil {
+		user = *req.User
+	}
+
+	viewErr := authz.CanViewTrack(user, track)
+	if viewErr == nil && (req.User != nil || !s.previews) {
+		return true, nil
+	}
+
+	if !track.Hidden && track.Visibility != models.VisibilityPrivate && req.ShareToken != "" && s.shareVerifier != nil {
+		consume := req.Rendition != RenditionPreview && (req.File == indexFile || req.File == mpdFile) && req.Range == nil
+		err := s.shareVerifier.ValidateShare(ctx, req.ShareToken, track.ID, consume)
+		if err == nil {
+			return true, nil
+		}
+		if viewErr != nil {
+			return false, err
+		}
+	}
+
+	if viewErr != nil {
+		return false, authz.ErrForbidden
+	}
+
+	return false, nil
+}
+
+// countsPlay reports whether the request starts a play of the full track. Players fetch the media playlist
+// once per play, previews and range requests aren't plays.
+func countsPlay(req Request, full bool) bool {
+	return full && req.Rendition != RenditionPreview && req.File == indexFile && req.Range == nil
+}
+
+// renditionPrefix returns where the files of the rendition the listener gets are stored.
+func (s *StreamService) renditionPrefix(ctx context.Context, req Request, track models.Track, full bool) (string, error) {
+	switch {
+	case req.Rendition == RenditionPreview || !full:
+		if track.PreviewPrefix == nil {
+			return "", ErrNoPreview
+		}
+		return *track.PreviewPrefix, nil
+	case req.Rendition == RenditionLossless:
+		if req.User == nil {
+			return "", fmt.Errorf("%w: %w", ErrAccessDenied, authz.ErrForbidden)
+		}
+		if s.entitlements != nil {
+			if err := s.entitlements.CheckLossless(ctx, *req.User); err != nil {
+				return "", fmt.Errorf("%w: %w", ErrAccessDenied, err)
+			}
+		}
+		if track.LosslessPrefix == nil {
+			return "", ErrNoLossless
+		}
+		return *track.LosslessPrefix, nil
+	default:
+		return *track.HLSPrefix, nil
+	}
+}
+
+// manifestRewriter returns the URI rewriter of an HLS playlist or a DASH manifest, nil for media files.
+func manifestRewriter(file string) func(r io.Reader, rewrite func(uri string) string) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".m3u8":
+		return media.RewritePlaylist
+	case ".mpd":
+		return media.RewriteMPD
+	default:
+		return nil
+	}
+}
+
+// signManifest appends the stream and share tokens to every URI in the manifest so the player can fetch segments with them.
+// The validators of the stored object are dropped, they don't describe the rewritten body and a revalidated copy
+// would keep the tokens of an earlier request.
+func signManifest(
+	rc io.ReadCloser,
+	info storage.ObjectInfo,
+	rewriter func(r io.Reader, rewrite func(uri string) string) ([]byte, error),
+	token, share string,
+) (io.ReadCloser, storage.ObjectInfo, error) {
+	defer rc.Close()
+
+	data, err := rewriter(rc, func(uri string) string {
+		if token != "" {
+			uri = media.AppendQuery(uri, tokenParam, token)
+		}
+		if share != "" {
+			uri = media.AppendQuery(uri, shareParam, share)
+		}
+		return uri
+	})
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	info.Size = int64(len(data))
+	info.ETag = ""
+	info.LastModified = time.Time{}
+
+	return io.NopCloser(bytes.NewReader(data)), info, nil
 }
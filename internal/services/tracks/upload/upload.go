@@ -1,7 +1,9 @@
 package upload
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,24 +11,57 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
 	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tags"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	coversvc "github.com/Sheridanlk/Music-Service/internal/services/tracks/cover"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	ErrInvalidVisibility = errors.New("invalid visibility")
+	ErrInvalidCover      = errors.New("invalid cover")
+	ErrInvalidTags       = errors.New("invalid tags")
+	ErrUnknownGenre      = errors.New("unknown genre")
+)
+
+// Cover is an image uploaded together with a track. The worker resizes it in place of the embedded art.
+type Cover struct {
+	Reader io.Reader
+	Size   int64
+}
+
+// Taxonomy files a track under the genre with the slug Genre and tags it, both are optional.
+type Taxonomy struct {
+	Genre string
+	Tags  []string
+}
+
 type UploadService struct {
 	log *slog.Logger
 
 	trackSaver   TrackProvider
 	mediaSaver   MediaSaver
 	taskProducer TaskProducer
+	quotaChecker QuotaChecker
 
 	originalBucket string
 }
 
 type TrackProvider interface {
-	SaveTrack(ctx context.Context, title, originBucket string) (int64, error)
+	SaveTrack(ctx context.Context, title, originBucket string, uploadedBy int64, visibility string) (int64, error)
 	SetOrginKey(ctx context.Context, id int64, originKey string) error
+	SetTrackSize(ctx context.Context, id int64, size int64) error
 	SetStatusPending(ctx context.Context, id int64) error
 	SetStatusError(ctx context.Context, id int64) error
+	GenreBySlug(ctx context.Context, slug string) (models.Genre, error)
+	SetTrackGenre(ctx context.Context, id int64, genreID *int64) error
+	SetTrackTags(ctx context.Context, id int64, tags []string) error
 }
 
 type MediaSaver interface {
@@ -37,24 +72,86 @@ type TaskProducer interface {
 	SendTrackTask(ctx context.Context, trackId string) error
 }
 
-func New(log *slog.Logger, trackProvider TrackProvider, mediaSaver MediaSaver, taskProducer TaskProducer, originalBucket string) *UploadService {
+type QuotaChecker interface {
+	CheckUpload(ctx context.Context, user models.User, size int64) (int64, error)
+	CheckStored(ctx context.Context, user models.User) error
+}
+
+// New creates an upload service. Quotas are not enforced when quotaChecker is nil.
+func New(log *slog.Logger, trackProvider TrackProvider, mediaSaver MediaSaver, taskProducer TaskProducer, quotaChecker QuotaChecker, originalBucket string) *UploadService {
 	return &UploadService{
 		log:            log,
 		trackSaver:     trackProvider,
 		mediaSaver:     mediaSaver,
 		taskProducer:   taskProducer,
+		quotaChecker:   quotaChecker,
 		originalBucket: originalBucket,
 	}
 }
 
-func (s *UploadService) UploadTrack(ctx context.Context, title string, filename string, reader io.Reader, size int64) (int64, error) {
+// UploadTrack stores the original file and the optional cover and queues the track for processing.
+// An empty visibility means the track is public.
+func (s *UploadService) UploadTrack(ctx context.Context, user models.User, title, visibility string, filename string, reader io.Reader, size int64, cover *Cover, taxonomy Taxonomy) (_ int64, err error) {
 	const op = "tracks.UploadTrack"
 
+	ctx, span := tracing.Tracer().Start(ctx, op, trace.WithAttributes(
+		attribute.Int64("user_id", user.ID),
+		attribute.Int64("size", size),
+	))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.String("filename", filename),
+		slog.Int64("user_id", user.ID),
 	)
 
+	if err := authz.CanUpload(user); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+	if !authz.ValidVisibility(visibility) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidVisibility)
+	}
+
+	if s.quotaChecker != nil {
+		if _, err := s.quotaChecker.CheckUpload(ctx, user, size); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	var coverData []byte
+	var coverType string
+	if cover != nil {
+		coverData, coverType, err = readCover(cover)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	trackTags, err := tags.Normalize(taxonomy.Tags)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w: %w", op, ErrInvalidTags, err)
+	}
+
+	var genreID *int64
+	if taxonomy.Genre != "" {
+		genre, err := s.trackSaver.GenreBySlug(ctx, taxonomy.Genre)
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, fmt.Errorf("%s: %w", op, ErrUnknownGenre)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: failed to get genre: %w", op, err)
+		}
+		genreID = &genre.ID
+	}
+
 	title = strings.TrimSpace(title)
 	if title == "" {
 		title = filename
@@ -67,17 +164,30 @@ func (s *UploadService) UploadTrack(ctx context.Context, title string, filename
 
 	log.Info("starting track upload")
 
-	id, err := s.trackSaver.SaveTrack(ctx, title, s.originalBucket)
+	id, err := s.trackSaver.SaveTrack(ctx, title, s.originalBucket, user.ID, visibility)
 	if err != nil {
 		return 0, fmt.Errorf("%s: failed to save track: %w", op, err)
 	}
 
+	span.SetAttributes(attribute.Int64("track_id", id))
+
 	defer func() {
 		if err != nil {
 			_ = s.trackSaver.SetStatusError(ctx, id) != nil
 		}
 	}()
 
+	if genreID != nil {
+		if err := s.trackSaver.SetTrackGenre(ctx, id, genreID); err != nil {
+			return 0, fmt.Errorf("%s: failed to save genre: %w", op, err)
+		}
+	}
+	if len(trackTags) > 0 {
+		if err := s.trackSaver.SetTrackTags(ctx, id, trackTags); err != nil {
+			return 0, fmt.Errorf("%s: failed to save tags: %w", op, err)
+		}
+	}
+
 	originKey := media.GenerateTrackOriginKey(id, ext)
 	if err := s.trackSaver.SetOrginKey(ctx, id, originKey); err != nil {
 		return 0, fmt.Errorf("%s: failed to save origin key: %w", op, err)
@@ -107,6 +217,24 @@ func (s *UploadService) UploadTrack(ctx context.Context, title string, filename
 	}
 
 	log.Info("original file uploaded successfully")
+	metrics.UploadBytes.Add(float64(size))
+
+	if coverData != nil {
+		coverKey := media.GenerateTrackCoverSourceKey(id)
+		if err := s.mediaSaver.PutObject(ctx, s.originalBucket, coverKey, bytes.NewReader(coverData), int64(len(coverData)), coverType); err != nil {
+			return 0, fmt.Errorf("%s: failed to upload cover: %w", op, err)
+		}
+	}
+
+	if err := s.trackSaver.SetTrackSize(ctx, id, size); err != nil {
+		return 0, fmt.Errorf("%s: failed to save track size: %w", op, err)
+	}
+
+	if s.quotaChecker != nil {
+		if err := s.quotaChecker.CheckStored(ctx, user); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
 	if err := s.trackSaver.SetStatusPending(ctx, id); err != nil {
 		return 0, fmt.Errorf("%s: failed to set track status pending: %w", op, err)
@@ -121,3 +249,25 @@ func (s *UploadService) UploadTrack(ctx context.Context, title string, filename
 
 	return id, nil
 }
+
+// readCover reads the uploaded cover and checks it is an image the worker can decode.
+func readCover(cover *Cover) ([]byte, string, error) {
+	if cover.Size > coversvc.MaxSourceSize {
+		return nil, "", fmt.Errorf("%w: too large", ErrInvalidCover)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(cover.Reader, coversvc.MaxSourceSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read cover: %w", err)
+	}
+	if len(data) > coversvc.MaxSourceSize {
+		return nil, "", fmt.Errorf("%w: too large", ErrInvalidCover)
+	}
+
+	format, err := media.CoverFormat(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidCover, err)
+	}
+
+	return data, "image/" + format, nil
+}
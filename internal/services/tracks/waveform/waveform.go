@@ -0,0 +1,209 @@
+package waveform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/Sheridanlk/Music-Service/internal/authz"
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+)
+
+// Waveforms are stored in the audiowaveform JSON and binary (.dat) layouts.
+const (
+	FormatJSON = "json"
+	FormatDat  = "dat"
+)
+
+var (
+	ErrTrackNotFound    = errors.New("track not found")
+	ErrWaveformNotFound = errors.New("waveform not found")
+	ErrResolution       = errors.New("unsupported resolution")
+	ErrFormat           = errors.New("unsupported format")
+	ErrAccessDenied     = errors.New("access denied")
+)
+
+// Request describes a waveform request. User is nil for anonymous listeners.
+type Request struct {
+	TrackID    int64
+	Resolution int
+	Format     string
+	ShareToken string
+	User       *models.User
+}
+
+type WaveformService struct {
+	log *slog.Logger
+
+	trackProvider TrackProvider
+	mediaProvider MediaProvider
+	shareVerifier ShareVerifier
+
+	bucket  string
+	profile Profile
+}
+
+// Profile describes the computed peaks. Resolutions are samples per pixel,
+// the coarsest one is served when the request doesn't ask for a resolution.
+type Profile struct {
+	SampleRate  int
+	Resolutions []int
+}
+
+type TrackProvider interface {
+	GetTrack(ctx context.Context, id int64) (models.Track, error)
+	GetOriginKey(ctx context.Context, id int64) (string, string, error)
+}
+
+type MediaProvider interface {
+	GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, storage.ObjectInfo, error)
+	PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error
+}
+
+type ShareVerifier interface {
+	ValidateShare(ctx context.Context, raw string, trackID int64, consume bool) error
+}
+
+// New creates a waveform service. The worker computes waveforms and passes a nil shareVerifier,
+// the service serves them and needs it to let share link holders see unlisted tracks.
+func New(log *slog.Logger, trackProvider TrackProvider, mediaProvider MediaProvider, shareVerifier ShareVerifier, bucket string, profile Profile) *WaveformService {
+	return &WaveformService{
+		log:           log,
+		trackProvider: trackProvider,
+		mediaProvider: mediaProvider,
+		shareVerifier: shareVerifier,
+		bucket:        bucket,
+		profile:       profile,
+	}
+}
+
+// Generate computes the peaks of the original file at every resolution of the profile
+// and stores them in both formats next to the HLS files.
+func (s *WaveformService) Generate(ctx context.Context, id int64) error {
+	const op = "waveform.Generate"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int64("track_id", id),
+	)
+
+	bucket, originKey, err := s.trackProvider.GetOriginKey(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get origin key: %w", op, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("waveform-%d-*", id))
+	if err != nil {
+		return fmt.Errorf("%s: failed to create temp dir: %w", op, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localOriginal := filepath.Join(tmpDir, "original"+filepath.Ext(originKey))
+
+	body, _, err := s.mediaProvider.GetObject(ctx, bucket, originKey, nil)
+	if err != nil {
+		return fmt.Errorf("%s: failed to download original track: %w", op, err)
+	}
+	defer body.Close()
+
+	if err := media.WriteToFile(localOriginal, body); err != nil {
+		return fmt.Errorf("%s: failed to save original track to local file: %w", op, err)
+	}
+
+	log.Info("computing waveforms", slog.Any("resolutions", s.profile.Resolutions))
+
+	waveforms, err := media.ComputeWaveforms(ctx, localOriginal, s.profile.SampleRate, s.profile.Resolutions)
+	if err != nil {
+		return fmt.Errorf("%s: failed to compute waveforms: %w", op, err)
+	}
+
+	for _, wf := range waveforms {
+		jsonData, err := wf.MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("%s: failed to encode waveform: %w", op, err)
+		}
+		datData, err := wf.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%s: failed to encode waveform: %w", op, err)
+		}
+
+		for format, data := range map[string][]byte{FormatJSON: jsonData, FormatDat: datData} {
+			key := media.GenerateTrackWaveformKey(id, wf.SamplesPerPixel, format)
+			if err := s.mediaProvider.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), media.DetectContentType(key)); err != nil {
+				return fmt.Errorf("%s: failed to upload waveform: %w", op, err)
+			}
+		}
+	}
+
+	log.Info("waveforms stored")
+
+	return nil
+}
+
+// GetWaveform returns the stored peaks of a track the requester may view.
+func (s *WaveformService) GetWaveform(ctx context.Context, req Request) (io.ReadCloser, storage.ObjectInfo, error) {
+	const op = "waveform.GetWaveform"
+
+	if req.Format == "" {
+		req.Format = FormatJSON
+	}
+	if req.Format != FormatJSON && req.Format != FormatDat {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrFormat)
+	}
+
+	if req.Resolution == 0 && len(s.profile.Resolutions) > 0 {
+		req.Resolution = slices.Max(s.profile.Resolutions)
+	}
+	if !slices.Contains(s.profile.Resolutions, req.Resolution) {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrResolution)
+	}
+
+	track, err := s.trackProvider.GetTrack(ctx, req.TrackID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrTrackNotFound)
+	}
+	if err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to get track: %w", op, err)
+	}
+
+	if err := s.checkAccess(ctx, req, track); err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w: %w", op, ErrAccessDenied, err)
+	}
+
+	key := media.GenerateTrackWaveformKey(track.ID, req.Resolution, req.Format)
+	rc, info, err := s.mediaProvider.GetObject(ctx, s.bucket, key, nil)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, ErrWaveformNotFound)
+	}
+	if err != nil {
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: failed to get waveform: %w", op, err)
+	}
+
+	return rc, info, nil
+}
+
+// checkAccess follows the stream rules but never counts a share link play.
+func (s *WaveformService) checkAccess(ctx context.Context, req Request, track models.Track) error {
+	var user models.User
+	if req.User != nil {
+		user = *req.User
+	}
+
+	if err := authz.CanViewTrack(user, track); err == nil {
+		return nil
+	}
+
+	if track.Hidden || track.Visibility == models.VisibilityPrivate || req.ShareToken == "" || s.shareVerifier == nil {
+		return authz.ErrForbidden
+	}
+
+	return s.shareVerifier.ValidateShare(ctx, req.ShareToken, track.ID, false)
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"time"
 
 	"github.com/Sheridanlk/Music-Service/internal/lib/media"
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
 	"github.com/Sheridanlk/Music-Service/internal/storage"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MinioStorage struct {
@@ -33,10 +38,18 @@ func New(endpoint string, accessKeyID string, secretAccessKey string, useSSL boo
 	}, nil
 }
 
-func (s *MinioStorage) PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) error {
+func (s *MinioStorage) PutObject(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, contentType string) (err error) {
 	const op = "storage.minio.Upload"
 
-	_, err := s.minioclient.PutObject(
+	defer func(start time.Time) { metrics.ObserveMinIO("put_object", start, err) }(time.Now())
+
+	ctx, span := startSpan(ctx, "minio PutObject", bucketName, objectName)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	_, err = s.minioclient.PutObject(
 		ctx,
 		bucketName,
 		objectName,
@@ -51,9 +64,17 @@ func (s *MinioStorage) PutObject(ctx context.Context, bucketName, objectName str
 	return nil
 }
 
-func (s *MinioStorage) GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (io.ReadCloser, string, int64, error) {
+func (s *MinioStorage) GetObject(ctx context.Context, bucketName, objectName string, byteRange *storage.ByteRange) (_ io.ReadCloser, _ storage.ObjectInfo, err error) {
 	const op = "storage.minio.Download"
 
+	defer func(start time.Time) { metrics.ObserveMinIO("get_object", start, err) }(time.Now())
+
+	ctx, span := startSpan(ctx, "minio GetObject", bucketName, objectName)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	opts := minio.GetObjectOptions{}
 	if byteRange != nil {
 		opts.SetRange(byteRange.Start, byteRange.End)
@@ -61,16 +82,94 @@ func (s *MinioStorage) GetObject(ctx context.Context, bucketName, objectName str
 
 	obj, err := s.minioclient.GetObject(ctx, bucketName, objectName, opts)
 	if err != nil {
-		return nil, "", 0, fmt.Errorf("%s: can't download object: %w", op, err)
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: can't download object: %w", op, err)
 	}
 
 	st, err := obj.Stat()
 	if err != nil {
 		obj.Close()
-		return nil, "", 0, fmt.Errorf("%s: can't get object stats: %w", op, err)
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, storage.ObjectInfo{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+		return nil, storage.ObjectInfo{}, fmt.Errorf("%s: can't get object stats: %w", op, err)
+	}
+
+	info := storage.ObjectInfo{
+		Key:          objectName,
+		ContentType:  media.DetectContentType(objectName),
+		Size:         st.Size,
+		ETag:         st.ETag,
+		LastModified: st.LastModified,
+	}
+
+	return obj, info, nil
+}
+
+// ListObjects returns all objects whose key starts with prefix.
+func (s *MinioStorage) ListObjects(ctx context.Context, bucketName, prefix string) (_ []storage.ObjectInfo, err error) {
+	const op = "storage.minio.ListObjects"
+
+	defer func(start time.Time) { metrics.ObserveMinIO("list_objects", start, err) }(time.Now())
+
+	var objects []storage.ObjectInfo
+	for obj := range s.minioclient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("%s: can't list objects: %w", op, obj.Err)
+		}
+		objects = append(objects, storage.ObjectInfo{
+			Key:          obj.Key,
+			ContentType:  media.DetectContentType(obj.Key),
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+		})
 	}
 
-	ct := media.DetectContentType(objectName)
+	return objects, nil
+}
+
+// RemoveObjects deletes the objects and returns the first error reported by MinIO.
+func (s *MinioStorage) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) (err error) {
+	const op = "storage.minio.RemoveObjects"
 
-	return obj, ct, st.Size, nil
+	defer func(start time.Time) { metrics.ObserveMinIO("remove_objects", start, err) }(time.Now())
+
+	objects := make(chan minio.ObjectInfo, len(objectNames))
+	for _, name := range objectNames {
+		objects <- minio.ObjectInfo{Key: name}
+	}
+	close(objects)
+
+	for res := range s.minioclient.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		if res.Err != nil {
+			return fmt.Errorf("%s: can't remove %s: %w", op, res.ObjectName, res.Err)
+		}
+	}
+
+	return nil
+}
+
+// CheckBucket returns an error when the bucket is missing or MinIO can't be reached.
+func (s *MinioStorage) CheckBucket(ctx context.Context, bucketName string) error {
+	const op = "storage.minio.CheckBucket"
+
+	ok, err := s.minioclient.BucketExists(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s: bucket %s does not exist", op, bucketName)
+	}
+
+	return nil
+}
+
+func startSpan(ctx context.Context, name, bucketName, objectName string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("minio.bucket", bucketName),
+			attribute.String("minio.object", objectName),
+		),
+	)
 }
@@ -0,0 +1,117 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) SaveAPIKey(ctx context.Context, userID int64, name, prefix string, keyHash []byte, scopes []string) (models.APIKey, error) {
+	const op = "storage.postgresql.SaveAPIKey"
+
+	key := models.APIKey{
+		UserID: userID,
+		Name:   name,
+		Prefix: prefix,
+		Scopes: scopes,
+	}
+
+	err := s.pool.QueryRow(
+		ctx,
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		userID, name, prefix, keyHash, scopes,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return key, fmt.Errorf("%s: can't insert api key: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) APIKeyByHash(ctx context.Context, keyHash []byte) (models.APIKey, error) {
+	const op = "storage.postgresql.APIKeyByHash"
+
+	var key models.APIKey
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = $1`,
+		keyHash,
+	).Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return key, fmt.Errorf("%s: can't get api key: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns up to limit keys of the user newest first, after the cursor when it is set.
+func (s *Storage) ListAPIKeys(ctx context.Context, userID int64, limit int, after *models.Cursor) ([]models.APIKey, error) {
+	const op = "storage.postgresql.ListAPIKeys"
+
+	keys := make([]models.APIKey, 0, limit)
+
+	afterCreated, afterID := newestAfter(after)
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys
+		WHERE user_id = $1 AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4))
+		ORDER BY created_at DESC, id DESC LIMIT $2`,
+		userID, limit, afterCreated, afterID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get api keys: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64, userID int64) error {
+	const op = "storage.postgresql.RevokeAPIKey"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't revoke api key: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) TouchAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.TouchAPIKey"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't update last used: %w", op, err)
+	}
+
+	return nil
+}
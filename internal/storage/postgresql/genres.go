@@ -0,0 +1,207 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const foreignKeyViolation = "23503"
+
+// ListGenres returns up to limit genres ordered by name, after the cursor when it is set.
+func (s *Storage) ListGenres(ctx context.Context, limit int, after *models.Cursor) ([]models.Genre, error) {
+	const op = "storage.postgresql.ListGenres"
+
+	var afterName *string
+	var afterID *int64
+	if after != nil {
+		afterName, afterID = &after.Name, &after.ID
+	}
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT id, parent_id, slug, name, created_at FROM genres
+		WHERE $2::text IS NULL OR (name, id) > ($2, $3)
+		ORDER BY name, id LIMIT $1`,
+		limit, afterName, afterID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get genres: %w", op, err)
+	}
+
+	return scanGenres(rows, op)
+}
+
+// AllGenres returns the whole genre tree, parents are not guaranteed to come before their children.
+func (s *Storage) AllGenres(ctx context.Context) ([]models.Genre, error) {
+	const op = "storage.postgresql.AllGenres"
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT id, parent_id, slug, name, created_at FROM genres ORDER BY name, id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get genres: %w", op, err)
+	}
+
+	return scanGenres(rows, op)
+}
+
+func scanGenres(rows pgx.Rows, op string) ([]models.Genre, error) {
+	defer rows.Close()
+
+	genres := make([]models.Genre, 0)
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(&genre.ID, &genre.ParentID, &genre.Slug, &genre.Name, &genre.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		genres = append(genres, genre)
+	}
+
+	return genres, rows.Err()
+}
+
+func (s *Storage) GenreBySlug(ctx context.Context, slug string) (models.Genre, error) {
+	const op = "storage.postgresql.GenreBySlug"
+
+	var genre models.Genre
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT id, parent_id, slug, name, created_at FROM genres WHERE slug = $1`,
+		slug,
+	).Scan(&genre.ID, &genre.ParentID, &genre.Slug, &genre.Name, &genre.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return genre, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return genre, fmt.Errorf("%s: can't get genre: %w", op, err)
+	}
+
+	return genre, nil
+}
+
+func (s *Storage) SaveGenre(ctx context.Context, genre models.Genre) (int64, error) {
+	const op = "storage.postgresql.SaveGenre"
+
+	var id int64
+
+	err := s.pool.QueryRow(
+		ctx,
+		`INSERT INTO genres (parent_id, slug, name) VALUES ($1, $2, $3) RETURNING id`,
+		genre.ParentID, genre.Slug, genre.Name,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, genreError(err, storage.ErrNotFound))
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UpdateGenre(ctx context.Context, genre models.Genre) error {
+	const op = "storage.postgresql.UpdateGenre"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE genres SET parent_id = $1, slug = $2, name = $3 WHERE id = $4`,
+		genre.ParentID, genre.Slug, genre.Name, genre.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, genreError(err, storage.ErrNotFound))
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+// DeleteGenre removes a genre without subgenres, its tracks are left without a genre.
+func (s *Storage) DeleteGenre(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.DeleteGenre"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`DELETE FROM genres WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, genreError(err, storage.ErrGenreInUse))
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetTrackGenre(ctx context.Context, id int64, genreID *int64) error {
+	const op = "storage.postgresql.SetTrackGenre"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET genre_id = $1 WHERE id = $2`,
+		genreID, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, genreError(err, storage.ErrNotFound))
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SetTrackTags replaces all tags of the track in a single transaction.
+func (s *Storage) SetTrackTags(ctx context.Context, id int64, tags []string) error {
+	const op = "storage.postgresql.SetTrackTags"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: can't begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM track_tags WHERE track_id = $1`, id); err != nil {
+		return fmt.Errorf("%s: can't delete old tags: %w", op, err)
+	}
+
+	if len(tags) > 0 {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO track_tags (track_id, tag) SELECT $1, unnest($2::text[])`,
+			id, tags,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: can't insert tags: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: can't commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// genreError maps constraint violations of the genre tree to storage errors, fkErr is what a foreign key
+// violation means to the caller: a missing parent or genre, or a deleted genre that still has subgenres.
+func genreError(err error, fkErr error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return storage.ErrGenreExists
+		case foreignKeyViolation:
+			return fkErr
+		}
+	}
+	return fmt.Errorf("can't write genre: %w", err)
+}
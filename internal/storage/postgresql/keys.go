@@ -0,0 +1,72 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveTrackKeys stores the encryption keys of a processing run in a single transaction.
+// Keys of other runs are left alone, the rendition they encrypt may still be the published one.
+func (s *Storage) SaveTrackKeys(ctx context.Context, trackID int64, run string, keys []models.TrackKey) error {
+	const op = "storage.postgresql.SaveTrackKeys"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: can't begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, k := range keys {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO track_keys (track_id, run, key_index, key_material) VALUES ($1, $2, $3, $4)`,
+			trackID, run, k.Index, k.Material,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: can't insert key: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: can't commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteStaleTrackKeys deletes the encryption keys of every run of the track except keepRun.
+func (s *Storage) DeleteStaleTrackKeys(ctx context.Context, trackID int64, keepRun string) error {
+	const op = "storage.postgresql.DeleteStaleTrackKeys"
+
+	_, err := s.pool.Exec(ctx, `DELETE FROM track_keys WHERE track_id = $1 AND run <> $2`, trackID, keepRun)
+	if err != nil {
+		return fmt.Errorf("%s: can't delete keys: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetTrackKey(ctx context.Context, trackID int64, run string, index int) (models.TrackKey, error) {
+	const op = "storage.postgresql.GetTrackKey"
+
+	key := models.TrackKey{TrackID: trackID, Run: run, Index: index}
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT key_material FROM track_keys WHERE track_id = $1 AND run = $2 AND key_index = $3`,
+		trackID, run, index,
+	).Scan(&key.Material)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return key, fmt.Errorf("%s: can't get track key: %w", op, err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,80 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) GetLyrics(ctx context.Context, trackID int64) (models.Lyrics, error) {
+	const op = "storage.postgresql.GetLyrics"
+
+	var lyrics models.Lyrics
+	var language *string
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT track_id, format, body, language, updated_at FROM track_lyrics WHERE track_id = $1`,
+		trackID,
+	).Scan(&lyrics.TrackID, &lyrics.Format, &lyrics.Text, &language, &lyrics.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return lyrics, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return lyrics, fmt.Errorf("%s: can't get lyrics: %w", op, err)
+	}
+
+	if language != nil {
+		lyrics.Language = *language
+	}
+
+	return lyrics, nil
+}
+
+// SaveLyrics replaces the lyrics of the track.
+func (s *Storage) SaveLyrics(ctx context.Context, lyrics models.Lyrics) error {
+	const op = "storage.postgresql.SaveLyrics"
+
+	var language *string
+	if lyrics.Language != "" {
+		language = &lyrics.Language
+	}
+
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO track_lyrics (track_id, format, body, language) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (track_id) DO UPDATE SET
+			format = EXCLUDED.format,
+			body = EXCLUDED.body,
+			language = EXCLUDED.language,
+			updated_at = NOW()`,
+		lyrics.TrackID, lyrics.Format, lyrics.Text, language,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't save lyrics: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteLyrics(ctx context.Context, trackID int64) error {
+	const op = "storage.postgresql.DeleteLyrics"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`DELETE FROM track_lyrics WHERE track_id = $1`,
+		trackID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't delete lyrics: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,346 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NilVersion is the schema version of a database without applied migrations.
+const NilVersion int64 = -1
+
+// migrationLockID is the advisory lock key held while migrations run, so replicas
+// starting at the same time apply them once.
+const migrationLockID int64 = 0x6d75736963 // "music"
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+
+	up   string
+	down string
+}
+
+// MigrationState is the schema version recorded in schema_migrations and the known migrations.
+type MigrationState struct {
+	Version    int64
+	Dirty      bool
+	Migrations []Migration
+}
+
+// The schema_migrations table has the layout used by golang-migrate, so databases
+// migrated by the migrate CLI keep working.
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)`
+
+// MigrationStatus returns the current schema version and the migrations found in fsys.
+func (s *Storage) MigrationStatus(ctx context.Context, fsys fs.FS) (MigrationState, error) {
+	const op = "storage.postgresql.MigrationStatus"
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return MigrationState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	state := MigrationState{Migrations: migrations}
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := migrationVersion(ctx, conn)
+		state.Version, state.Dirty = version, dirty
+		return err
+	})
+	if err != nil {
+		return MigrationState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return state, nil
+}
+
+// MigrateUp applies all migrations newer than the current version and returns their versions.
+func (s *Storage) MigrateUp(ctx context.Context, fsys fs.FS) ([]int64, error) {
+	const op = "storage.postgresql.MigrateUp"
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var applied []int64
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := migrationVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		pending, err := pendingUp(migrations, version, dirty)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range pending {
+			if err := runMigration(ctx, conn, m.Version, m.up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m.Version)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return applied, nil
+}
+
+// MigrateDown rolls back the last n applied migrations and returns their versions.
+func (s *Storage) MigrateDown(ctx context.Context, fsys fs.FS, n int) ([]int64, error) {
+	const op = "storage.postgresql.MigrateDown"
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var reverted []int64
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := migrationVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		steps, err := pendingDown(migrations, version, dirty, n)
+		if err != nil {
+			return err
+		}
+
+		for _, step := range steps {
+			if err := runMigration(ctx, conn, step.target, step.migration.down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", step.migration.Version, step.migration.Name, err)
+			}
+			reverted = append(reverted, step.migration.Version)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return reverted, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reverted, nil
+}
+
+// ForceVersion records version as applied and clears the dirty flag without running any SQL.
+func (s *Storage) ForceVersion(ctx context.Context, fsys fs.FS, version int64) error {
+	const op = "storage.postgresql.ForceVersion"
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	known := version == NilVersion
+	for _, m := range migrations {
+		if m.Version == version {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("%s: version %d: %w", op, version, storage.ErrUnknownMigration)
+	}
+
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		return setMigrationVersion(ctx, conn, version, false)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// downStep reverts migration and leaves the database at the target version.
+type downStep struct {
+	migration Migration
+	target    int64
+}
+
+// pendingUp returns the migrations newer than version in the order they are applied.
+func pendingUp(migrations []Migration, version int64, dirty bool) ([]Migration, error) {
+	if dirty {
+		return nil, fmt.Errorf("version %d: %w", version, storage.ErrDirtyMigration)
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// pendingDown returns the steps reverting the last n applied migrations, newest first.
+// The current version must be one of the known migrations.
+func pendingDown(migrations []Migration, version int64, dirty bool, n int) ([]downStep, error) {
+	if dirty {
+		return nil, fmt.Errorf("version %d: %w", version, storage.ErrDirtyMigration)
+	}
+
+	var steps []downStep
+	for i := len(migrations) - 1; i >= 0 && len(steps) < n; i-- {
+		m := migrations[i]
+		if m.Version > version {
+			continue
+		}
+		if m.Version != version {
+			return nil, fmt.Errorf("version %d: %w", version, storage.ErrUnknownMigration)
+		}
+
+		prev := NilVersion
+		if i > 0 {
+			prev = migrations[i-1].Version
+		}
+
+		steps = append(steps, downStep{migration: m, target: prev})
+		version = prev
+	}
+
+	return steps, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the session advisory lock.
+func (s *Storage) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("can't acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("can't take migration lock: %w", err)
+	}
+	defer func() {
+		// The lock must be released even if ctx is already cancelled.
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("can't create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func migrationVersion(ctx context.Context, conn *pgxpool.Conn) (int64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NilVersion, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("can't read schema version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+func setMigrationVersion(ctx context.Context, conn *pgxpool.Conn, version int64, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return fmt.Errorf("can't set schema version: %w", err)
+	}
+	if version >= 0 || dirty {
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty); err != nil {
+			return fmt.Errorf("can't set schema version: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// runMigration marks the database dirty at the target version, runs the script and clears the flag.
+// A failed script leaves the database dirty until the version is forced.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, target int64, script string) error {
+	if err := setMigrationVersion(ctx, conn, target, true); err != nil {
+		return err
+	}
+
+	// Without arguments pgx uses the simple protocol, which runs a multi-statement script in one implicit transaction.
+	if _, err := conn.Exec(ctx, script); err != nil {
+		return err
+	}
+
+	return setMigrationVersion(ctx, conn, target, false)
+}
+
+// loadMigrations reads the up and down scripts from fsys sorted by version.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("can't read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", entry.Name(), err)
+		}
+
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("can't read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up or down script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
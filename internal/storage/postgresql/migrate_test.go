@@ -0,0 +1,216 @@
+package postgresql
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/Sheridanlk/Music-Service/migrations"
+)
+
+func script(sql string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(sql)}
+}
+
+func versions(ms []Migration) []int64 {
+	out := make([]int64, len(ms))
+	for i, m := range ms {
+		out[i] = m.Version
+	}
+	return out
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []int64
+		wantErr bool
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"000010_genres.up.sql":   script("CREATE TABLE genres ();"),
+				"000010_genres.down.sql": script("DROP TABLE genres;"),
+				"000002_users.up.sql":    script("CREATE TABLE users ();"),
+				"000002_users.down.sql":  script("DROP TABLE users;"),
+				"000001_init.up.sql":     script("CREATE TABLE tracks ();"),
+				"000001_init.down.sql":   script("DROP TABLE tracks;"),
+			},
+			want: []int64{1, 2, 10},
+		},
+		{
+			name: "other files ignored",
+			fsys: fstest.MapFS{
+				"000001_init.up.sql":     script("CREATE TABLE tracks ();"),
+				"000001_init.down.sql":   script("DROP TABLE tracks;"),
+				"README.md":              script("notes"),
+				"init.up.sql":            script("SELECT 1;"),
+				"000002_seed.sql":        script("SELECT 1;"),
+				"000003_dir.up.sql/x":    script("SELECT 1;"),
+				"000004_draft.up.sql.bk": script("SELECT 1;"),
+			},
+			want: []int64{1},
+		},
+		{
+			name: "missing down script",
+			fsys: fstest.MapFS{
+				"000001_init.up.sql": script("CREATE TABLE tracks ();"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing up script",
+			fsys: fstest.MapFS{
+				"000001_init.down.sql": script("DROP TABLE tracks;"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "version out of range",
+			fsys: fstest.MapFS{
+				"99999999999999999999_huge.up.sql":   script("SELECT 1;"),
+				"99999999999999999999_huge.down.sql": script("SELECT 1;"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "no migrations",
+			fsys: fstest.MapFS{},
+			want: []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadMigrations(tt.fsys)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("loadMigrations() = %v, want error", versions(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations() error = %v", err)
+			}
+			if !slices.Equal(versions(got), tt.want) {
+				t.Fatalf("loadMigrations() versions = %v, want %v", versions(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationsScripts(t *testing.T) {
+	got, err := loadMigrations(fstest.MapFS{
+		"000001_init_schema.up.sql":   script("CREATE TABLE tracks ();"),
+		"000001_init_schema.down.sql": script("DROP TABLE tracks;"),
+	})
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+
+	want := Migration{Version: 1, Name: "init_schema", up: "CREATE TABLE tracks ();", down: "DROP TABLE tracks;"}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("loadMigrations() = %+v, want %+v", got, want)
+	}
+}
+
+// TestEmbeddedMigrations keeps the shipped migrations loadable and numbered without gaps.
+func TestEmbeddedMigrations(t *testing.T) {
+	got, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	for i, m := range got {
+		if m.Version != int64(i+1) {
+			t.Fatalf("migration %d_%s follows version %d", m.Version, m.Name, i)
+		}
+	}
+}
+
+func TestPendingUp(t *testing.T) {
+	all := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+
+	tests := []struct {
+		name    string
+		version int64
+		dirty   bool
+		want    []int64
+		wantErr error
+	}{
+		{name: "empty database", version: NilVersion, want: []int64{1, 2, 3}},
+		{name: "partly migrated", version: 1, want: []int64{2, 3}},
+		{name: "up to date", version: 3},
+		{name: "newer than the binary", version: 7},
+		{name: "dirty", version: 2, dirty: true, wantErr: storage.ErrDirtyMigration},
+		{name: "dirty empty database", version: NilVersion, dirty: true, wantErr: storage.ErrDirtyMigration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pendingUp(all, tt.version, tt.dirty)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("pendingUp() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pendingUp() error = %v", err)
+			}
+			if !slices.Equal(versions(got), tt.want) {
+				t.Fatalf("pendingUp() = %v, want %v", versions(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingDown(t *testing.T) {
+	all := []Migration{{Version: 1}, {Version: 2}, {Version: 5}}
+
+	type step struct {
+		version, target int64
+	}
+
+	tests := []struct {
+		name    string
+		version int64
+		dirty   bool
+		n       int
+		want    []step
+		wantErr error
+	}{
+		{name: "last migration", version: 5, n: 1, want: []step{{5, 2}}},
+		{name: "several migrations", version: 5, n: 2, want: []step{{5, 2}, {2, 1}}},
+		{name: "down to an empty database", version: 2, n: 10, want: []step{{2, 1}, {1, NilVersion}}},
+		{name: "empty database", version: NilVersion, n: 1},
+		{name: "unknown version", version: 3, n: 1, wantErr: storage.ErrUnknownMigration},
+		{name: "newer than the binary", version: 7, n: 1, wantErr: storage.ErrUnknownMigration},
+		{name: "dirty", version: 5, dirty: true, n: 1, wantErr: storage.ErrDirtyMigration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pendingDown(all, tt.version, tt.dirty, tt.n)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("pendingDown() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pendingDown() error = %v", err)
+			}
+
+			steps := make([]step, len(got))
+			for i, s := range got {
+				steps[i] = step{s.migration.Version, s.target}
+			}
+			if !slices.Equal(steps, tt.want) {
+				t.Fatalf("pendingDown() = %v, want %v", steps, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -33,6 +34,7 @@ func New(host, userName, password, dbName string, port int) (*Storage, error) {
 	cfg.MaxConnLifetime = 30 * time.Minute
 	cfg.MaxConnIdleTime = 5 * time.Minute
 	cfg.HealthCheckPeriod = 1 * time.Minute
+	cfg.ConnConfig.Tracer = multitracer.New(metricsTracer{}, spanTracer{})
 
 	ctx, cansel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cansel()
@@ -51,6 +53,16 @@ func New(host, userName, password, dbName string, port int) (*Storage, error) {
 	return &Storage{pool: pool}, nil
 }
 
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgresql.Ping"
+
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (s *Storage) Close() {
 	s.pool.Close()
 }
@@ -0,0 +1,154 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) UserPlan(ctx context.Context, userID int64) (string, error) {
+	const op = "storage.postgresql.UserPlan"
+
+	var plan string
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT plan FROM users WHERE id = $1`,
+		userID,
+	).Scan(&plan)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: can't get plan: %w", op, err)
+	}
+
+	return plan, nil
+}
+
+func (s *Storage) SetUserPlan(ctx context.Context, userID int64, plan string) error {
+	const op = "storage.postgresql.SetUserPlan"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE users SET plan = $1 WHERE id = $2`,
+		plan, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set plan: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UserQuotaOverride returns the per-user limits. A user without overrides gets an empty override.
+func (s *Storage) UserQuotaOverride(ctx context.Context, userID int64) (models.QuotaOverride, error) {
+	const op = "storage.postgresql.UserQuotaOverride"
+
+	var (
+		override    models.QuotaOverride
+		durationSec *int
+	)
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT max_bytes, max_tracks, max_file_bytes, max_duration_seconds FROM user_quotas WHERE user_id = $1`,
+		userID,
+	).Scan(&override.MaxBytes, &override.MaxTracks, &override.MaxFileBytes, &durationSec)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.QuotaOverride{}, nil
+	}
+	if err != nil {
+		return override, fmt.Errorf("%s: can't get quota override: %w", op, err)
+	}
+
+	if durationSec != nil {
+		d := time.Duration(*durationSec) * time.Second
+		override.MaxDuration = &d
+	}
+
+	return override, nil
+}
+
+func (s *Storage) SetUserQuotaOverride(ctx context.Context, userID int64, override models.QuotaOverride) error {
+	const op = "storage.postgresql.SetUserQuotaOverride"
+
+	var durationSec *int
+	if override.MaxDuration != nil {
+		sec := int(override.MaxDuration.Seconds())
+		durationSec = &sec
+	}
+
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO user_quotas (user_id, max_bytes, max_tracks, max_file_bytes, max_duration_seconds) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			max_bytes = EXCLUDED.max_bytes,
+			max_tracks = EXCLUDED.max_tracks,
+			max_file_bytes = EXCLUDED.max_file_bytes,
+			max_duration_seconds = EXCLUDED.max_duration_seconds,
+			updated_at = NOW()`,
+		userID, override.MaxBytes, override.MaxTracks, override.MaxFileBytes, durationSec,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't save quota override: %w", op, err)
+	}
+
+	return nil
+}
+
+// StorageUsage sums the tracks of the user. Failed tracks don't count against the quota.
+func (s *Storage) StorageUsage(ctx context.Context, userID int64) (models.StorageUsage, error) {
+	const op = "storage.postgresql.StorageUsage"
+
+	var usage models.StorageUsage
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT COALESCE(SUM(size_bytes), 0), COUNT(*) FROM tracks WHERE uploaded_by = $1 AND status <> 'error'`,
+		userID,
+	).Scan(&usage.Bytes, &usage.Tracks)
+	if err != nil {
+		return usage, fmt.Errorf("%s: can't get usage: %w", op, err)
+	}
+
+	return usage, nil
+}
+
+func (s *Storage) SetTrackSize(ctx context.Context, id int64, size int64) error {
+	const op = "storage.postgresql.SetTrackSize"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET size_bytes = $1 WHERE id = $2`,
+		size, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set size: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetTrackDuration(ctx context.Context, id int64, duration time.Duration) error {
+	const op = "storage.postgresql.SetTrackDuration"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET duration_seconds = $1 WHERE id = $2`,
+		duration.Seconds(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set duration: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/ratelimit"
+	"github.com/jackc/pgx/v5"
+)
+
+// rateLimitRetention is how long an idle bucket is kept. Buckets are refilled long before that.
+const rateLimitRetention = 24 * time.Hour
+
+// TakeToken implements the shared rate limit store, so all replicas count requests against the same buckets.
+func (s *Storage) TakeToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	const op = "storage.postgresql.TakeToken"
+
+	var res ratelimit.Result
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO rate_limits (key, tokens, updated_at) VALUES ($1, $2, NOW()) ON CONFLICT (key) DO NOTHING`,
+			key, float64(limit.Burst),
+		)
+		if err != nil {
+			return err
+		}
+
+		var (
+			tokens    float64
+			updatedAt time.Time
+			now       time.Time
+		)
+
+		err = tx.QueryRow(
+			ctx,
+			`SELECT tokens, updated_at, NOW() FROM rate_limits WHERE key = $1 FOR UPDATE`,
+			key,
+		).Scan(&tokens, &updatedAt, &now)
+		if err != nil {
+			return err
+		}
+
+		tokens, res = ratelimit.Take(tokens, updatedAt, now, limit)
+
+		_, err = tx.Exec(
+			ctx,
+			`UPDATE rate_limits SET tokens = $1, updated_at = $2 WHERE key = $3`,
+			tokens, now, key,
+		)
+
+		return err
+	})
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// PurgeRateLimits removes buckets that were not used for a day.
+func (s *Storage) PurgeRateLimits(ctx context.Context) error {
+	const op = "storage.postgresql.PurgeRateLimits"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`DELETE FROM rate_limits WHERE updated_at < $1`,
+		time.Now().Add(-rateLimitRetention),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) SaveShareLink(ctx context.Context, trackID, createdBy int64, tokenHash []byte, expiresAt *time.Time, maxPlays *int) (models.ShareLink, error) {
+	const op = "storage.postgresql.SaveShareLink"
+
+	link := models.ShareLink{
+		TrackID:   trackID,
+		CreatedBy: &createdBy,
+		ExpiresAt: expiresAt,
+		MaxPlays:  maxPlays,
+	}
+
+	err := s.pool.QueryRow(
+		ctx,
+		`INSERT INTO share_links (track_id, created_by, token_hash, expires_at, max_plays) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		trackID, createdBy, tokenHash, expiresAt, maxPlays,
+	).Scan(&link.ID, &link.CreatedAt)
+	if err != nil {
+		return link, fmt.Errorf("%s: can't insert share link: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (s *Storage) ShareLinkByHash(ctx context.Context, tokenHash []byte) (models.ShareLink, error) {
+	const op = "storage.postgresql.ShareLinkByHash"
+
+	var link models.ShareLink
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT id, track_id, created_by, expires_at, max_plays, plays, last_played_at, revoked_at, created_at FROM share_links WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&link.ID, &link.TrackID, &link.CreatedBy, &link.ExpiresAt, &link.MaxPlays, &link.Plays, &link.LastPlayedAt, &link.RevokedAt, &link.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return link, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return link, fmt.Errorf("%s: can't get share link: %w", op, err)
+	}
+
+	return link, nil
+}
+
+// ListShareLinks returns up to limit links of the track newest first, after the cursor when it is set.
+func (s *Storage) ListShareLinks(ctx context.Context, trackID int64, limit int, after *models.Cursor) ([]models.ShareLink, error) {
+	const op = "storage.postgresql.ListShareLinks"
+
+	links := make([]models.ShareLink, 0, limit)
+
+	afterCreated, afterID := newestAfter(after)
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT id, track_id, created_by, expires_at, max_plays, plays, last_played_at, revoked_at, created_at FROM share_links
+		WHERE track_id = $1 AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4))
+		ORDER BY created_at DESC, id DESC LIMIT $2`,
+		trackID, limit, afterCreated, afterID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get share links: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link models.ShareLink
+		if err := rows.Scan(&link.ID, &link.TrackID, &link.CreatedBy, &link.ExpiresAt, &link.MaxPlays, &link.Plays, &link.LastPlayedAt, &link.RevokedAt, &link.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) RevokeShareLink(ctx context.Context, id int64, trackID int64) error {
+	const op = "storage.postgresql.RevokeShareLink"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE share_links SET revoked_at = NOW() WHERE id = $1 AND track_id = $2 AND revoked_at IS NULL`,
+		id, trackID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't revoke share link: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+// ConsumeSharePlay counts a play of the link. It returns storage.ErrNotFound when the link
+// is revoked, expired or out of plays, so concurrent plays can't exceed the limit.
+func (s *Storage) ConsumeSharePlay(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.ConsumeSharePlay"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE share_links SET plays = plays + 1, last_played_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND (max_plays IS NULL OR plays < max_plays)`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't count share play: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package postgresql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/lib/metrics"
+	"github.com/Sheridanlk/Music-Service/internal/lib/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	at        time.Time
+	operation string
+}
+
+// metricsTracer records the latency of every query labeled by its statement type.
+type metricsTracer struct{}
+
+func (metricsTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{
+		at:        time.Now(),
+		operation: statementType(data.SQL),
+	})
+}
+
+func (metricsTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	metrics.PostgresDuration.WithLabelValues(start.operation, metrics.Outcome(data.Err)).Observe(time.Since(start.at).Seconds())
+}
+
+// spanTracer wraps every query in a client span. Query arguments are never recorded.
+type spanTracer struct{}
+
+func (spanTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracing.Tracer().Start(ctx, "postgres "+statementType(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (spanTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	tracing.RecordError(span, data.Err)
+	span.End()
+}
+
+func statementType(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	switch op := strings.ToLower(fields[0]); op {
+	case "select", "insert", "update", "delete", "begin", "commit", "rollback":
+		return op
+	default:
+		return "other"
+	}
+}
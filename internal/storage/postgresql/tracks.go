@@ -2,21 +2,26 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Sheridanlk/Music-Service/internal/domain/models"
 	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
 )
 
-func (s *Storage) SaveTrack(ctx context.Context, title, originBucket string) (int64, error) {
+func (s *Storage) SaveTrack(ctx context.Context, title, originBucket string, uploadedBy int64, visibility string) (int64, error) {
 	const op = "storage.postgresql.SaveTrack"
 
 	var id int64
 
 	err := s.pool.QueryRow(
 		ctx,
-		`INSERT INTO tracks (title, origin_bucket) VALUES ($1, $2) RETURNING id`,
-		title, originBucket,
+		`INSERT INTO tracks (title, origin_bucket, uploaded_by, visibility) VALUES ($1, $2, $3, $4) RETURNING id`,
+		title, originBucket, uploadedBy, visibility,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("%s: can't insert track: %w", op, err)
@@ -59,16 +64,32 @@ func (s *Storage) GetTrack(ctx context.Context, id int64) (models.Track, error)
 	const op = "storage.postgresql.GetTrack"
 
 	var track models.Track
+	var previewStart, duration *float64
 
 	err := s.pool.QueryRow(
 		ctx,
-		`SELECT id, title, created_at, origin_bucket, origin_key, hls_bucket FROM tracks WHERE id = $1`,
+		`SELECT id, title, created_at, origin_bucket, origin_key, hls_bucket, hls_prefix, uploaded_by, hidden, visibility, status, size_bytes, gain_db, has_cover,
+			preview_start_seconds, preview_prefix, lossless_prefix, has_dash, duration_seconds, genre_id
+		FROM tracks WHERE id = $1`,
 		id,
-	).Scan(&track.ID, &track.Title, &track.CreatedAt, &track.OriginBucket, &track.OriginKey, &track.HLSBucket)
+	).Scan(&track.ID, &track.Title, &track.CreatedAt, &track.OriginBucket, &track.OriginKey, &track.HLSBucket, &track.HLSPrefix, &track.UploadedBy, &track.Hidden, &track.Visibility, &track.Status, &track.SizeBytes, &track.GainDB, &track.HasCover,
+		&previewStart, &track.PreviewPrefix, &track.LosslessPrefix, &track.HasDASH, &duration, &track.GenreID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return track, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
 	if err != nil {
 		return track, fmt.Errorf("%s: can't get track: %w", op, err)
 	}
 
+	if previewStart != nil {
+		start := time.Duration(*previewStart * float64(time.Second))
+		track.PreviewStart = &start
+	}
+	if duration != nil {
+		d := time.Duration(*duration * float64(time.Second))
+		track.Duration = &d
+	}
+
 	return track, nil
 }
 
@@ -132,15 +153,33 @@ func (s *Storage) ListAllTracks(ctx context.Context, count int, offset int) ([]m
 	return tracks, nil
 }
 
-func (s *Storage) ListReadyTracks(ctx context.Context, count int, offset int) ([]models.TrackListItem, error) {
+// ListReadyTracks returns ready public tracks together with all ready tracks of the viewer
+// that match the filter. Anonymous viewers have ViewerID 0.
+func (s *Storage) ListReadyTracks(ctx context.Context, filter models.TrackFilter) ([]models.TrackListItem, error) {
 	const op = "storage.postgresql.ListTracks"
 
-	tracks := make([]models.TrackListItem, 0, count)
+	where, args := readyTracksWhere(filter)
+
+	offset := filter.Offset
+	if filter.After != nil {
+		var after string
+		after, args = keysetAfter(filter.Sort, *filter.After, args)
+		where += " AND " + after
+		offset = 0
+	}
+	args = append(args, filter.Limit, offset)
+
+	tracks := make([]models.TrackListItem, 0, filter.Limit)
 
 	rows, err := s.pool.Query(
 		ctx,
-		`SELECT id, title, created_at FROM tracks WHERE status = 'ready' ORDER BY created_at DESC LIMIT $1 OFFSET $2`,
-		count, offset,
+		`SELECT t.id, t.title, t.created_at, t.gain_db, t.has_cover, t.preview_prefix IS NOT NULL, t.lossless_prefix IS NOT NULL, t.has_dash,
+			t.uploaded_by, g.slug, ARRAY(SELECT tag FROM track_tags WHERE track_id = t.id ORDER BY tag), t.duration_seconds, t.plays
+		FROM tracks t LEFT JOIN genres g ON g.id = t.genre_id
+		WHERE `+where+`
+		ORDER BY `+trackOrder(filter.Sort)+`
+		LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)),
+		args...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("%s: can't get tracks: %w", op, err)
@@ -149,13 +188,248 @@ func (s *Storage) ListReadyTracks(ctx context.Context, count int, offset int) ([
 
 	for rows.Next() {
 		var track models.TrackListItem
-		if err := rows.Scan(&track.ID, &track.Title, &track.CreatedAt); err != nil {
+		var duration *float64
+		if err := rows.Scan(&track.ID, &track.Title, &track.CreatedAt, &track.GainDB, &track.HasCover, &track.HasPreview, &track.HasLossless, &track.HasDASH,
+			&track.ArtistID, &track.Genre, &track.Tags, &duration, &track.Plays); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
+		if duration != nil {
+			d := time.Duration(*duration * float64(time.Second))
+			track.Duration = &d
+		}
 		tracks = append(tracks, track)
 	}
 
-	return tracks, nil
+	return tracks, rows.Err()
+}
+
+// TrackFacets counts the tracks ListReadyTracks would return for the filter without paging,
+// broken down by genre and by the most used tags.
+func (s *Storage) TrackFacets(ctx context.Context, filter models.TrackFilter, maxTags int) (models.TrackFacets, error) {
+	const op = "storage.postgresql.TrackFacets"
+
+	var facets models.TrackFacets
+
+	where, args := readyTracksWhere(filter)
+
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM tracks t WHERE `+where, args...).Scan(&facets.Total)
+	if err != nil {
+		return facets, fmt.Errorf("%s: can't count tracks: %w", op, err)
+	}
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT g.slug, g.name, COUNT(*) FROM tracks t JOIN genres g ON g.id = t.genre_id
+		WHERE `+where+`
+		GROUP BY g.slug, g.name ORDER BY COUNT(*) DESC, g.name`,
+		args...,
+	)
+	if err != nil {
+		return facets, fmt.Errorf("%s: can't count genres: %w", op, err)
+	}
+	facets.Genres, err = scanFacets(rows, true)
+	if err != nil {
+		return facets, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err = s.pool.Query(
+		ctx,
+		`SELECT tt.tag, COUNT(*) FROM tracks t JOIN track_tags tt ON tt.track_id = t.id
+		WHERE `+where+`
+		GROUP BY tt.tag ORDER BY COUNT(*) DESC, tt.tag LIMIT $`+strconv.Itoa(len(args)+1),
+		append(args, maxTags)...,
+	)
+	if err != nil {
+		return facets, fmt.Errorf("%s: can't count tags: %w", op, err)
+	}
+	facets.Tags, err = scanFacets(rows, false)
+	if err != nil {
+		return facets, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return facets, nil
+}
+
+// IncrementPlays counts a play of the full track.
+func (s *Storage) IncrementPlays(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.IncrementPlays"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET plays = plays + 1 WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't count play: %w", op, err)
+	}
+
+	return nil
+}
+
+// readyTracksWhere builds the condition of the listing over tracks aliased t with its positional arguments.
+func readyTracksWhere(filter models.TrackFilter) (string, []any) {
+	args := []any{filter.ViewerID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conds := []string{
+		`t.status = 'ready'`,
+		`((t.visibility = 'public' AND NOT t.hidden) OR t.uploaded_by = $1)`,
+	}
+
+	if filter.Genre != "" {
+		conds = append(conds, `t.genre_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM genres WHERE slug = `+arg(filter.Genre)+`
+				UNION ALL
+				SELECT sub.id FROM genres sub JOIN tree ON sub.parent_id = tree.id
+			)
+			SELECT id FROM tree
+		)`)
+	}
+	if len(filter.Tags) > 0 {
+		conds = append(conds, `(SELECT COUNT(*) FROM track_tags WHERE track_id = t.id AND tag = ANY(`+arg(filter.Tags)+`)) = `+arg(len(filter.Tags)))
+	}
+	if filter.ArtistID != 0 {
+		conds = append(conds, `t.uploaded_by = `+arg(filter.ArtistID))
+	}
+	if filter.DurationMin != nil {
+		conds = append(conds, `t.duration_seconds >= `+arg(filter.DurationMin.Seconds()))
+	}
+	if filter.DurationMax != nil {
+		conds = append(conds, `t.duration_seconds <= `+arg(filter.DurationMax.Seconds()))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// keysetAfter returns the condition selecting the tracks after the cursor in the order of trackOrder,
+// its arguments are appended to args.
+func keysetAfter(sort string, after models.TrackCursor, args []any) (string, []any) {
+	n := len(args)
+	p := func(i int) string { return "$" + strconv.Itoa(n+i) }
+
+	switch sort {
+	case models.SortTitle:
+		return `(lower(t.title), t.id) > (lower(` + p(1) + `), ` + p(2) + `)`, append(args, after.Title, after.ID)
+	case models.SortPopularity:
+		return `(t.plays, t.created_at, t.id) < (` + p(1) + `, ` + p(2) + `, ` + p(3) + `)`, append(args, after.Plays, after.CreatedAt, after.ID)
+	default:
+		return `(t.created_at, t.id) < (` + p(1) + `, ` + p(2) + `)`, append(args, after.CreatedAt, after.ID)
+	}
+}
+
+// newestAfter returns the arguments of a newest first keyset condition, both nil without a cursor.
+func newestAfter(after *models.Cursor) (*time.Time, *int64) {
+	if after == nil {
+		return nil, nil
+	}
+	return &after.CreatedAt, &after.ID
+}
+
+// newestKeyset returns the arguments of a newest first keyset condition, both nil without a cursor.
+func newestKeyset(after *models.TrackCursor) (*time.Time, *int64) {
+	if after == nil {
+		return nil, nil
+	}
+	return &after.CreatedAt, &after.ID
+}
+
+// trackOrder returns the ORDER BY clause of the sort, ties are broken by id so pages don't overlap.
+func trackOrder(sort string) string {
+	switch sort {
+	case models.SortTitle:
+		return `lower(t.title), t.id`
+	case models.SortPopularity:
+		return `t.plays DESC, t.created_at DESC, t.id DESC`
+	default:
+		return `t.created_at DESC, t.id DESC`
+	}
+}
+
+func scanFacets(rows pgx.Rows, named bool) ([]models.Facet, error) {
+	defer rows.Close()
+
+	facets := []models.Facet{}
+	for rows.Next() {
+		var facet models.Facet
+		var err error
+		if named {
+			err = rows.Scan(&facet.Value, &facet.Name, &facet.Count)
+		} else {
+			err = rows.Scan(&facet.Value, &facet.Count)
+		}
+		if err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+
+	return facets, rows.Err()
+}
+
+// ListTracksByStatus returns tracks with all their fields, newest first. An empty status matches every track.
+// The page starts after the cursor when it is set, offset is ignored then.
+func (s *Storage) ListTracksByStatus(ctx context.Context, status string, count int, offset int, after *models.TrackCursor) ([]models.Track, error) {
+	const op = "storage.postgresql.ListTracksByStatus"
+
+	tracks := make([]models.Track, 0, count)
+
+	afterCreated, afterID := newestKeyset(after)
+	if after != nil {
+		offset = 0
+	}
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT id, title, created_at, origin_bucket, origin_key, hls_bucket, hls_prefix, uploaded_by, hidden, visibility, status, size_bytes
+		FROM tracks
+		WHERE ($1 = '' OR status = $1) AND ($4::timestamptz IS NULL OR (created_at, id) < ($4, $5))
+		ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`,
+		status, count, offset, afterCreated, afterID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get tracks: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var track models.Track
+		if err := rows.Scan(&track.ID, &track.Title, &track.CreatedAt, &track.OriginBucket, &track.OriginKey, &track.HLSBucket, &track.HLSPrefix, &track.UploadedBy, &track.Hidden, &track.Visibility, &track.Status, &track.SizeBytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
+
+// ExistingTrackIDs reports which of ids belong to tracks that still exist.
+func (s *Storage) ExistingTrackIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	const op = "storage.postgresql.ExistingTrackIDs"
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT id FROM tracks WHERE id = ANY($1)`,
+		ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get tracks: %w", op, err)
+	}
+	defer rows.Close()
+
+	existing := make(map[int64]bool, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		existing[id] = true
+	}
+
+	return existing, rows.Err()
 }
 
 func (s *Storage) SetStatusPending(ctx context.Context, id int64) error {
@@ -228,6 +502,57 @@ func (s *Storage) SetStatusError(ctx context.Context, id int64) error {
 	return nil
 }
 
+// ResetStatusPending moves a finished or failed track back to pending so it can be processed again.
+func (s *Storage) ResetStatusPending(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.ResetStatusPending"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET status = $1 WHERE id = $2 AND status IN ('ready', 'error')`,
+		storage.StatusPending, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't reset status: %w", op, err)
+	}
+	if rowsAffected := res.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("%s: track not found or not in ready or error status", op)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetTrackHidden(ctx context.Context, id int64, hidden bool) error {
+	const op = "storage.postgresql.SetTrackHidden"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET hidden = $1 WHERE id = $2`,
+		hidden, id,
+	)
+
+	if err != nil {
+		return fmt.Errorf("%s: can't set hidden: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetTrackVisibility(ctx context.Context, id int64, visibility string) error {
+	const op = "storage.postgresql.SetTrackVisibility"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET visibility = $1 WHERE id = $2`,
+		visibility, id,
+	)
+
+	if err != nil {
+		return fmt.Errorf("%s: can't set visibility: %w", op, err)
+	}
+
+	return nil
+}
+
 func (s *Storage) DeleteTrack(ctx context.Context, id int64) error {
 	const op = "storage.postgresql.DeleteTrack"
 
@@ -259,3 +584,132 @@ func (s *Storage) EditTrack(ctx context.Context, id int64, title string) error {
 
 	return nil
 }
+
+func (s *Storage) CountTracksByStatus(ctx context.Context) (map[string]int, error) {
+	const op = "storage.postgresql.CountTracksByStatus"
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT status, COUNT(*) FROM tracks GROUP BY status`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't count tracks: %w", op, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			status string
+			n      int
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		counts[status] = n
+	}
+
+	return counts, rows.Err()
+}
+
+func (s *Storage) SetTrackLoudness(ctx context.Context, id int64, loudness models.Loudness) error {
+	const op = "storage.postgresql.SetTrackLoudness"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET loudness_lufs = $1, true_peak_dbtp = $2, loudness_range_lu = $3, gain_db = $4 WHERE id = $5`,
+		loudness.Integrated, loudness.TruePeak, loudness.Range, loudness.Gain, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set loudness: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetTrackCover(ctx context.Context, id int64, hasCover bool) error {
+	const op = "storage.postgresql.SetTrackCover"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET has_cover = $1 WHERE id = $2`,
+		hasCover, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set cover: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetPreviewStart stores the preview window picked by the owner, nil resets it to the middle of the track.
+func (s *Storage) SetPreviewStart(ctx context.Context, id int64, start *time.Duration) error {
+	const op = "storage.postgresql.SetPreviewStart"
+
+	var seconds *float64
+	if start != nil {
+		v := start.Seconds()
+		seconds = &v
+	}
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET preview_start_seconds = $1 WHERE id = $2`,
+		seconds, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set preview start: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetPreview(ctx context.Context, id int64, previewPrefix string) error {
+	const op = "storage.postgresql.SetPreview"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET preview_prefix = $1 WHERE id = $2`,
+		previewPrefix, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set preview: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetLossless stores the prefix of the lossless rendition, nil when the track has none.
+func (s *Storage) SetLossless(ctx context.Context, id int64, losslessPrefix *string) error {
+	const op = "storage.postgresql.SetLossless"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET lossless_prefix = $1 WHERE id = $2`,
+		losslessPrefix, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set lossless: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetDASH stores whether the renditions of the track come with an MPD manifest.
+func (s *Storage) SetDASH(ctx context.Context, id int64, hasDASH bool) error {
+	const op = "storage.postgresql.SetDASH"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`UPDATE tracks SET has_dash = $1 WHERE id = $2`,
+		hasDASH, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set dash: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sheridanlk/Music-Service/internal/domain/models"
+	"github.com/Sheridanlk/Music-Service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolation = "23505"
+
+func (s *Storage) SaveUser(ctx context.Context, email string, passwordHash []byte, role string) (int64, error) {
+	const op = "storage.postgresql.SaveUser"
+
+	var id int64
+
+	err := s.pool.QueryRow(
+		ctx,
+		`INSERT INTO users (email, password_hash, role) VALUES ($1, $2, $3) RETURNING id`,
+		email, passwordHash, role,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return 0, fmt.Errorf("%s: can't insert user: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.postgresql.UserByEmail"
+
+	var user models.User
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT id, email, role, password_hash, created_at FROM users WHERE email = $1`,
+		email,
+	).Scan(&user.ID, &user.Email, &user.Role, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return user, fmt.Errorf("%s: can't get user: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) UserByID(ctx context.Context, id int64) (models.User, error) {
+	const op = "storage.postgresql.UserByID"
+
+	var user models.User
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT id, email, role, password_hash, created_at FROM users WHERE id = $1`,
+		id,
+	).Scan(&user.ID, &user.Email, &user.Role, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return user, fmt.Errorf("%s: can't get user: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, id int64, role string) error {
+	const op = "storage.postgresql.SetUserRole"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE users SET role = $1 WHERE id = $2`,
+		role, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't set user role: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, userID int64, tokenHash []byte, expiresAt time.Time) error {
+	const op = "storage.postgresql.SaveRefreshToken"
+
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't insert refresh token: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.postgresql.RefreshToken"
+
+	var token models.RefreshToken
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.ExpiresAt, &token.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return token, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		return token, fmt.Errorf("%s: can't get refresh token: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshToken marks the token revoked. It returns storage.ErrNotFound when the token
+// does not exist or was already revoked, so concurrent refreshes with one token can't both succeed.
+func (s *Storage) RevokeRefreshToken(ctx context.Context, id int64) error {
+	const op = "storage.postgresql.RevokeRefreshToken"
+
+	res, err := s.pool.Exec(
+		ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't revoke refresh token: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
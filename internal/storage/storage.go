@@ -1,10 +1,34 @@
 package storage
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrUserExists = errors.New("user already exists")
+
+	ErrGenreExists = errors.New("genre already exists")
+	ErrGenreInUse  = errors.New("genre has subgenres")
+
+	ErrDirtyMigration   = errors.New("database is dirty, fix it and force the version")
+	ErrUnknownMigration = errors.New("unknown migration version")
+)
+
 type ByteRange struct {
 	Start int64
 	End   int64
 }
 
+type ObjectInfo struct {
+	Key          string
+	ContentType  string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
 const (
 	StatusReady      = "ready"
 	StatusError      = "error"
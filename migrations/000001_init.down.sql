@@ -1,2 +1,2 @@
-DROP tracks IF EXISTS;
-DROP INDEX IF EXISTS tracks_created_at_idx;
\ No newline at end of file
+DROP INDEX IF EXISTS tracks_created_at_idx;
+DROP TABLE IF EXISTS tracks;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS track_keys;
\ No newline at end of file
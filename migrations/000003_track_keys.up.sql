@@ -0,0 +1,8 @@
+CREATE TABLE track_keys (
+    track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    key_index INT NOT NULL,
+    key_material BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (track_id, key_index)
+);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tracks_uploaded_by_idx;
+ALTER TABLE tracks DROP COLUMN IF EXISTS uploaded_by;
+
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
\ No newline at end of file
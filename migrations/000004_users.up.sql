@@ -0,0 +1,21 @@
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    password_hash BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens(user_id);
+
+ALTER TABLE tracks ADD COLUMN uploaded_by BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX tracks_uploaded_by_idx ON tracks(uploaded_by);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'listener'
+    CHECK (role IN ('listener', 'artist', 'moderator', 'admin'));
+
+ALTER TABLE tracks ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
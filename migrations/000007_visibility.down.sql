@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS share_links;
+
+DROP INDEX IF EXISTS tracks_visibility_idx;
+ALTER TABLE tracks DROP COLUMN IF EXISTS visibility;
\ No newline at end of file
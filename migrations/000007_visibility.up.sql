@@ -0,0 +1,18 @@
+ALTER TABLE tracks ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'unlisted', 'private'));
+
+CREATE INDEX tracks_visibility_idx ON tracks(visibility);
+
+CREATE TABLE share_links (
+    id BIGSERIAL PRIMARY KEY,
+    track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    token_hash BYTEA NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ,
+    max_plays INT,
+    plays INT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX share_links_track_id_idx ON share_links(track_id);
\ No newline at end of file
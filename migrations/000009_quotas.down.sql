@@ -0,0 +1,6 @@
+ALTER TABLE tracks DROP COLUMN IF EXISTS duration_seconds;
+ALTER TABLE tracks DROP COLUMN IF EXISTS size_bytes;
+
+DROP TABLE IF EXISTS user_quotas;
+
+ALTER TABLE users DROP COLUMN IF EXISTS plan;
@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN plan VARCHAR(20) NOT NULL DEFAULT 'free';
+
+CREATE TABLE user_quotas (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    max_bytes BIGINT,
+    max_tracks INT,
+    max_file_bytes BIGINT,
+    max_duration_seconds INT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE tracks ADD COLUMN size_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE tracks ADD COLUMN duration_seconds DOUBLE PRECISION;
@@ -0,0 +1,4 @@
+ALTER TABLE tracks DROP COLUMN IF EXISTS gain_db;
+ALTER TABLE tracks DROP COLUMN IF EXISTS loudness_range_lu;
+ALTER TABLE tracks DROP COLUMN IF EXISTS true_peak_dbtp;
+ALTER TABLE tracks DROP COLUMN IF EXISTS loudness_lufs;
@@ -0,0 +1,4 @@
+ALTER TABLE tracks ADD COLUMN loudness_lufs DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN true_peak_dbtp DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN loudness_range_lu DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN gain_db DOUBLE PRECISION;
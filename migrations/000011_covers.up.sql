@@ -0,0 +1 @@
+ALTER TABLE tracks ADD COLUMN has_cover BOOLEAN NOT NULL DEFAULT false;
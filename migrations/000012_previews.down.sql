@@ -0,0 +1,2 @@
+ALTER TABLE tracks DROP COLUMN IF EXISTS preview_prefix;
+ALTER TABLE tracks DROP COLUMN IF EXISTS preview_start_seconds;
@@ -0,0 +1,2 @@
+ALTER TABLE tracks ADD COLUMN preview_start_seconds DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN preview_prefix TEXT;
@@ -0,0 +1 @@
+ALTER TABLE tracks ADD COLUMN lossless_prefix TEXT;
@@ -0,0 +1 @@
+ALTER TABLE tracks ADD COLUMN has_dash BOOLEAN NOT NULL DEFAULT false;
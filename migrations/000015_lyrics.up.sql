@@ -0,0 +1,7 @@
+CREATE TABLE track_lyrics (
+    track_id BIGINT PRIMARY KEY REFERENCES tracks(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL,
+    body TEXT NOT NULL,
+    language VARCHAR(35),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS track_tags;
+ALTER TABLE tracks DROP COLUMN IF EXISTS plays;
+ALTER TABLE tracks DROP COLUMN IF EXISTS genre_id;
+DROP TABLE IF EXISTS genres;
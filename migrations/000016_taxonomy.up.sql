@@ -0,0 +1,22 @@
+CREATE TABLE genres (
+    id BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES genres(id) ON DELETE RESTRICT,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX genres_parent_id_idx ON genres(parent_id);
+
+ALTER TABLE tracks ADD COLUMN genre_id BIGINT REFERENCES genres(id) ON DELETE SET NULL;
+ALTER TABLE tracks ADD COLUMN plays BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX tracks_genre_id_idx ON tracks(genre_id);
+
+CREATE TABLE track_tags (
+    track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL,
+    PRIMARY KEY (track_id, tag)
+);
+
+CREATE INDEX track_tags_tag_idx ON track_tags(tag);
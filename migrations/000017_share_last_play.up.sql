@@ -0,0 +1 @@
+ALTER TABLE share_links ADD COLUMN last_played_at TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+DELETE FROM track_keys WHERE run <> '';
+
+ALTER TABLE track_keys DROP CONSTRAINT track_keys_pkey;
+ALTER TABLE track_keys ADD PRIMARY KEY (track_id, key_index);
+
+ALTER TABLE track_keys DROP COLUMN IF EXISTS run;
@@ -0,0 +1,4 @@
+ALTER TABLE track_keys ADD COLUMN run VARCHAR(32) NOT NULL DEFAULT '';
+
+ALTER TABLE track_keys DROP CONSTRAINT track_keys_pkey;
+ALTER TABLE track_keys ADD PRIMARY KEY (track_id, run, key_index);